@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// bodyFormats are the encodings -format can send request items as
+var bodyFormats = map[string]string{
+	"json":     "application/json",
+	"msgpack":  "application/msgpack",
+	"cbor":     "application/cbor",
+	"protobuf": "application/x-protobuf",
+}
+
+// encodeItems encodes the request items, gathered as JSON, in format
+func encodeItems(items interface{}, format string) ([]byte, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	if format == "protobuf" {
+		return encodeProtobuf(data)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v = fromJSON(v)
+	switch format {
+	case "msgpack":
+		return msgpack.Marshal(v)
+	case "cbor":
+		return cbor.Marshal(v)
+	}
+	return nil, fmt.Errorf("unsupported body format %q", format)
+}
+
+// fromJSON turns JSON numbers into integers where they are whole, as
+// binary formats distinguish them from floats.
+func fromJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = fromJSON(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromJSON(e)
+		}
+	}
+	return v
+}
+
+func decodeMsgpack(body []byte, _ string) ([]byte, error) {
+	r := bytes.NewReader(body)
+	dec := msgpack.NewDecoder(r)
+	// maps may have keys of any type, which toJSON turns into strings
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	var docs []interface{}
+	for r.Len() > 0 {
+		v, err := dec.DecodeInterface()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+	return marshalDocs(docs)
+}
+
+func decodeCBOR(body []byte, _ string) ([]byte, error) {
+	dec := cbor.NewDecoder(bytes.NewReader(body))
+	var docs []interface{}
+	for dec.NumBytesRead() < len(body) {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+	return marshalDocs(docs)
+}
+
+// marshalDocs writes each decoded value as JSON, one per line if there
+// are several, as for a stream of records.
+func marshalDocs(docs []interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	for i, doc := range docs {
+		data, err := json.Marshal(toJSON(doc))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		out.Write(data)
+	}
+	return out.Bytes(), nil
+}
+
+// toJSON makes decoded values representable in JSON, as msgpack and
+// CBOR allow map keys of any type, and floats that are not numbers.
+func toJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = toJSON(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = toJSON(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = toJSON(e)
+		}
+	case cbor.Tag:
+		return map[string]interface{}{"tag": t.Number, "value": toJSON(t.Content)}
+	case float32:
+		return toJSON(float64(t))
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return strconv.FormatFloat(t, 'g', -1, 64)
+		}
+	}
+	return v
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestDecodeBinary(t *testing.T) {
+	t.Parallel()
+	mustMsgpack := func(vs ...interface{}) []byte {
+		var out []byte
+		for _, v := range vs {
+			data, err := msgpack.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, data...)
+		}
+		return out
+	}
+	mustCBOR := func(vs ...interface{}) []byte {
+		var out []byte
+		for _, v := range vs {
+			data, err := cbor.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, data...)
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name    string
+		decode  func([]byte, string) ([]byte, error)
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "msgpack",
+			decode: decodeMsgpack,
+			input:  mustMsgpack(map[string]interface{}{"a": 1, "b": []interface{}{"x", true, nil}}),
+			want:   `{"a":1,"b":["x",true,null]}`,
+		},
+		{
+			name:   "msgpack keys that are not strings",
+			decode: decodeMsgpack,
+			input:  mustMsgpack(map[int]string{1: "one"}),
+			want:   `{"1":"one"}`,
+		},
+		{
+			name:   "msgpack records",
+			decode: decodeMsgpack,
+			input:  mustMsgpack(1, "two"),
+			want:   "1\n\"two\"",
+		},
+		{
+			name:   "msgpack floats that are not numbers",
+			decode: decodeMsgpack,
+			input:  mustMsgpack(math.Inf(1), float32(1.5)),
+			want:   "\"+Inf\"\n1.5",
+		},
+		{
+			name:    "truncated msgpack",
+			decode:  decodeMsgpack,
+			input:   mustMsgpack("hello")[:3],
+			wantErr: true,
+		},
+		{
+			name:   "cbor",
+			decode: decodeCBOR,
+			input:  mustCBOR(map[string]interface{}{"a": 1, "b": []interface{}{"x", 2.5}}),
+			want:   `{"a":1,"b":["x",2.5]}`,
+		},
+		{
+			name:   "cbor keys that are not strings",
+			decode: decodeCBOR,
+			input:  mustCBOR(map[int]string{1: "one"}),
+			want:   `{"1":"one"}`,
+		},
+		{
+			name:   "cbor tags",
+			decode: decodeCBOR,
+			input:  mustCBOR(cbor.Tag{Number: 1234, Content: "x"}),
+			want:   `{"tag":1234,"value":"x"}`,
+		},
+		{
+			name:   "cbor sequence",
+			decode: decodeCBOR,
+			input:  mustCBOR(1, math.NaN()),
+			want:   "1\n\"NaN\"",
+		},
+		{
+			name:    "truncated cbor",
+			decode:  decodeCBOR,
+			input:   mustCBOR("hello")[:3],
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := tc.decode(tc.input, "")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%v: got %s, wanted %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeItems(t *testing.T) {
+	t.Parallel()
+	items := map[string]interface{}{"n": 1, "f": 1.5, "s": []interface{}{"x"}}
+	testCases := []struct {
+		format string
+		decode func([]byte, string) ([]byte, error)
+	}{
+		{format: "msgpack", decode: decodeMsgpack},
+		{format: "cbor", decode: decodeCBOR},
+	}
+
+	for _, tc := range testCases {
+		data, err := encodeItems(items, tc.format)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.format, err)
+			continue
+		}
+		got, err := tc.decode(data, "")
+		if err != nil {
+			t.Errorf("%v: can't decode %v", tc.format, err)
+			continue
+		}
+		if want := `{"f":1.5,"n":1,"s":["x"]}`; string(got) != want {
+			t.Errorf("%v: got %s, wanted %s", tc.format, got, want)
+		}
+	}
+
+	// whole numbers are sent as integers rather than floats
+	data, err := encodeItems(map[string]interface{}{"n": 1}, "msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x81, 0xa1, 'n', 0xd3, 0, 0, 0, 0, 0, 0, 0, 1}; !bytes.Equal(data, want) {
+		t.Errorf("encoded % x, wanted % x", data, want)
+	}
+
+	if _, err := encodeItems(items, "xml"); err == nil {
+		t.Errorf("xml: encoded an unsupported format")
+	}
+}
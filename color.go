@@ -1,8 +1,6 @@
 package main
 
 import (
-	"fmt"
-	"log"
 	"regexp"
 	"strings"
 )
@@ -21,20 +19,30 @@ const (
 )
 
 func Color(str string, color uint8) string {
-	return fmt.Sprintf("%s%s%s", ColorStart(color), str, EndColor)
+	if palette[color] == "" {
+		return str
+	}
+	return ColorStart(color) + str + EndColor
 }
 
+// ColorStart returns the escape sequence for color in the current theme
 func ColorStart(color uint8) string {
-	return fmt.Sprintf("\033[%dm", color)
+	if palette[color] == "" {
+		return ""
+	}
+	return "\033[" + palette[color] + "m"
 }
 
 func ColorfulRequest(str string) string {
 	lines := strings.Split(str, "\n")
 	if printOption&printReqHeader == printReqHeader {
 		strs := strings.Split(lines[0], " ")
-		strs[0] = Color(strs[0], Magenta)
-		strs[1] = Color(strs[1], Cyan)
-		strs[2] = Color(strs[2], Magenta)
+		colors := []uint8{Magenta, Cyan, Magenta}
+		for i := range strs {
+			if i < len(colors) {
+				strs[i] = Color(strs[i], colors[i])
+			}
+		}
 		lines[0] = strings.Join(strs, " ")
 	}
 	for i, line := range lines[1:] {
@@ -50,105 +58,154 @@ func ColorfulRequest(str string) string {
 }
 
 func ColorfulResponse(str, contenttype string) string {
-	match, err := regexp.MatchString(contentJsonRegex, contenttype)
-	if err != nil {
-		log.Fatalln("failed to compile regex", err)
-	}
-	if match {
-		str = ColorfulJson(str)
-	} else {
-		str = ColorfulHTML(str)
+	if f, ok := formatterFor(contenttype); ok {
+		return f.color(str)
 	}
-	return str
+	return ColorfulHTML(str)
 }
 
+// ColorfulJson highlights JSON by its tokens, leaving its layout, and
+// anything that is not JSON, untouched: keys are magenta, strings cyan,
+// numbers yellow, and true, false and null blue.
 func ColorfulJson(str string) string {
-	var rsli []rune
-	var key, val, startcolor, endcolor, startsemicolon bool
-	var prev rune
-	for _, char := range []rune(str) {
-		switch char {
-		case ' ':
-			rsli = append(rsli, char)
-		case '{':
-			startcolor = true
-			key = true
-			val = false
-			rsli = append(rsli, char)
-		case '}':
-			startcolor = false
-			endcolor = false
-			key = false
-			val = false
-			rsli = append(rsli, char)
-		case '"':
-			if startsemicolon && prev == '\\' {
-				rsli = append(rsli, char)
-			} else {
-				if startcolor {
-					rsli = append(rsli, char)
-					if key {
-						rsli = append(rsli, []rune(ColorStart(Magenta))...)
-					} else if val {
-						rsli = append(rsli, []rune(ColorStart(Cyan))...)
-					}
-					startsemicolon = true
-					key = false
-					val = false
-					startcolor = false
-				} else {
-					rsli = append(rsli, []rune(EndColor)...)
-					rsli = append(rsli, char)
-					endcolor = true
-					startsemicolon = false
-				}
+	var out strings.Builder
+	out.Grow(len(str) * 2)
+	for i := 0; i < len(str); {
+		c := str[i]
+		switch {
+		case c == '"':
+			end := scanJSONString(str, i)
+			color := uint8(Cyan)
+			if isJSONKey(str, end) {
+				color = Magenta
 			}
-		case ',':
-			if !startsemicolon {
-				startcolor = true
-				key = true
-				val = false
-				if !endcolor {
-					rsli = append(rsli, []rune(EndColor)...)
-					endcolor = true
-				}
+			out.WriteString(Color(str[i:end], color))
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(str) && strings.IndexByte("0123456789+-.eE", str[end]) >= 0 {
+				end++
 			}
-			rsli = append(rsli, char)
-		case ':':
-			if !startsemicolon {
-				key = false
-				val = true
-				startcolor = true
-				if !endcolor {
-					rsli = append(rsli, []rune(EndColor)...)
-					endcolor = true
-				}
+			out.WriteString(Color(str[i:end], Yellow))
+			i = end
+		case c >= 'a' && c <= 'z':
+			end := i + 1
+			for end < len(str) && str[end] >= 'a' && str[end] <= 'z' {
+				end++
 			}
-			rsli = append(rsli, char)
-		case '\n', '\r', '[', ']':
-			rsli = append(rsli, char)
-		default:
-			if !startsemicolon {
-				if key && startcolor {
-					rsli = append(rsli, []rune(ColorStart(Magenta))...)
-					key = false
-					startcolor = false
-					endcolor = false
-				}
-				if val && startcolor {
-					rsli = append(rsli, []rune(ColorStart(Cyan))...)
-					val = false
-					startcolor = false
-					endcolor = false
-				}
+			switch word := str[i:end]; word {
+			case "true", "false", "null":
+				out.WriteString(Color(word, Blue))
+			default:
+				out.WriteString(word)
 			}
-			rsli = append(rsli, char)
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
 		}
-		prev = char
 	}
-	return string(rsli)
+	return out.String()
+}
+
+// scanJSONString returns the end of the string starting at i, just after
+// its closing quote, skipping escaped characters, or the end of str.
+func scanJSONString(str string, i int) int {
+	for i++; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(str)
+}
+
+// isJSONKey reports whether a string ending at i is followed by a colon
+func isJSONKey(str string, i int) bool {
+	for ; i < len(str); i++ {
+		switch str[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case ':':
+			return true
+		}
+		return false
+	}
+	return false
 }
 
 func ColorfulHTML(str string) string {
 	return Color(str, Green)
 }
+
+var (
+	tagPattern  = regexp.MustCompile(`^(</?)([^\s/>]+)([\s\S]*?)(/?>)$`)
+	attrPattern = regexp.MustCompile(`([^\s=/>]+)(\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+	yamlPattern = regexp.MustCompile(`^(\s*(?:- +)*)("[^"]*"|'[^']*'|[^\s#"'][^#]*?)(:)( +|$)(.*)$`)
+)
+
+// ColorfulXML colors tag names like JSON keys, attribute values like JSON
+// values, and comments and declarations gray, for XML and HTML alike.
+func ColorfulXML(str string) string {
+	return markupPattern.ReplaceAllStringFunc(str, func(tag string) string {
+		switch {
+		case strings.HasPrefix(tag, "<![CDATA["):
+			return tag
+		case tag[1] == '!' || tag[1] == '?':
+			return Color(tag, Gray)
+		}
+		m := tagPattern.FindStringSubmatch(tag)
+		if m == nil {
+			return tag
+		}
+		attrs := attrPattern.ReplaceAllString(m[3], Color("$1", Gray)+"$2"+Color("$3", Cyan))
+		return m[1] + Color(m[2], Magenta) + attrs + m[4]
+	})
+}
+
+// ColorfulYAML colors keys and values line by line, like JSON
+func ColorfulYAML(str string) string {
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"), trimmed == "---", trimmed == "...":
+			lines[i] = Color(line, Gray)
+		case yamlPattern.MatchString(line):
+			m := yamlPattern.FindStringSubmatch(line)
+			value := m[5]
+			if value != "" {
+				value = colorYAMLValue(value)
+			}
+			lines[i] = m[1] + Color(m[2], Magenta) + m[3] + m[4] + value
+		case strings.HasPrefix(trimmed, "- "):
+			indent := line[:strings.Index(line, "- ")+2]
+			lines[i] = indent + colorYAMLValue(line[len(indent):])
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// colorYAMLValue colors a scalar, leaving any trailing comment gray
+func colorYAMLValue(value string) string {
+	comment := ""
+	if i := strings.Index(value, " #"); i >= 0 {
+		value, comment = value[:i], Color(value[i:], Gray)
+	}
+	return Color(value, Cyan) + comment
+}
+
+// ColorfulForm colors the fields of a form, one per line, like JSON
+func ColorfulForm(str string) string {
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 {
+			lines[i] = Color(kv[0], Magenta) + "=" + Color(kv[1], Cyan)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestColorfulJson(t *testing.T) {
+	defer func(p theme) { palette = p }(palette)
+	palette = themes["default"]
+	key := func(s string) string { return Color(s, Magenta) }
+	str := func(s string) string { return Color(s, Cyan) }
+	num := func(s string) string { return Color(s, Yellow) }
+	lit := func(s string) string { return Color(s, Blue) }
+
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "object", input: `{"a": "b", "n": -1.5e3, "t": true, "z": null}`,
+			want: `{` + key(`"a"`) + `: ` + str(`"b"`) + `, ` + key(`"n"`) + `: ` + num(`-1.5e3`) + `, ` +
+				key(`"t"`) + `: ` + lit(`true`) + `, ` + key(`"z"`) + `: ` + lit(`null`) + `}`},
+		{name: "escaped quotes", input: `{"a\"b": "c\"d"}`,
+			want: `{` + key(`"a\"b"`) + `: ` + str(`"c\"d"`) + `}`},
+		{name: "escaped backslash", input: `{"a\\": "\\"}`,
+			want: `{` + key(`"a\\"`) + `: ` + str(`"\\"`) + `}`},
+		{name: "colon inside a string", input: `{"url": "http://x:80/"}`,
+			want: `{` + key(`"url"`) + `: ` + str(`"http://x:80/"`) + `}`},
+		{name: "colons in array values", input: `["a:b", "c"]`,
+			want: `[` + str(`"a:b"`) + `, ` + str(`"c"`) + `]`},
+		{name: "keys in nested arrays", input: "[{\"id\": 1}, [{\"k\" :\n\"v\"}]]",
+			want: `[{` + key(`"id"`) + `: ` + num(`1`) + `}, [{` + key(`"k"`) + " :\n" + str(`"v"`) + `}]]`},
+		{name: "key only by its colon", input: `{"a" "b"}`,
+			want: `{` + str(`"a"`) + ` ` + str(`"b"`) + `}`},
+		{name: "unterminated string", input: `{"a": "b`,
+			want: `{` + key(`"a"`) + `: ` + str(`"b`)},
+		{name: "other words", input: `nan`, want: `nan`},
+	}
+
+	for _, tc := range testCases {
+		if got := ColorfulJson(tc.input); got != tc.want {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got))
+		}
+	}
+}
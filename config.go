@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configSection sets flags, by their name on the command line, and
+// headers, which request items replace
+type configSection struct {
+	Flags   map[string]interface{} `json:"flags"`
+	Headers map[string]string      `json:"headers"`
+}
+
+// config is read from the config file, where hosts override the
+// top level settings for requests to them, and environments define the
+// variables of requests
+type config struct {
+	configSection
+	Hosts        map[string]configSection     `json:"hosts"`
+	Environments map[string]map[string]string `json:"environments"`
+}
+
+// configHeaders are sent with every request, before request items
+var configHeaders = make(map[string]string)
+
+// defaultConfigPath is $XDG_CONFIG_HOME/gurl/config.json, which is
+// ~/.config/gurl/config.json when XDG_CONFIG_HOME is unset
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gurl", "config.json")
+}
+
+// loadConfig applies the config file to every flag not given on the
+// command line, with the settings of the host of rawurl over the top
+// level ones, and selects the environment, whose variables rawurl may
+// use to name the host. The default file need not exist, while one
+// named with -config must.
+func loadConfig(path, rawurl string) {
+	if err := applyConfig(path, rawurl); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// applyConfig is loadConfig, returning any error
+func applyConfig(path, rawurl string) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		if environment != "" {
+			return fmt.Errorf("-env=%s: no environments, as there is no config %s", environment, path)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't read the config %v", err)
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	flags := make(map[string]interface{})
+	for k, v := range c.Flags {
+		flags[k] = v
+	}
+	for k, v := range c.Headers {
+		configHeaders[http.CanonicalHeaderKey(k)] = v
+	}
+	// the environment is chosen before the host, whose name may use it
+	if name, ok := c.Flags["env"].(string); ok && !flagGiven("env") {
+		environment = name
+	}
+	if environment != "" {
+		vars, ok := c.Environments[environment]
+		if !ok {
+			return fmt.Errorf("unknown environment %q in %s, use one of %s", environment, path, strings.Join(c.environmentNames(), ", "))
+		}
+		variables = vars
+	}
+	if host, ok := c.host(rawurl); ok {
+		if _, ok := host.Flags["env"]; ok {
+			return fmt.Errorf("invalid config %s: env can't be set per host", path)
+		}
+		for k, v := range host.Flags {
+			flags[k] = v
+		}
+		for k, v := range host.Headers {
+			configHeaders[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	if err := setFlags(flags); err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return nil
+}
+
+func (c config) environmentNames() []string {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// host returns the settings for the host of rawurl, matched by host and
+// port, by host name, and then by wildcards such as *.example.com
+func (c config) host(rawurl string) (configSection, bool) {
+	if rawurl == "" || len(c.Hosts) == 0 {
+		return configSection{}, false
+	}
+	rawurl, err := expandVars(rawurl)
+	if err != nil {
+		return configSection{}, false
+	}
+	u, err := url.Parse(expandURL(rawurl))
+	if err != nil {
+		return configSection{}, false
+	}
+	if s, ok := c.Hosts[u.Host]; ok {
+		return s, true
+	}
+	name := u.Hostname()
+	if s, ok := c.Hosts[name]; ok {
+		return s, true
+	}
+	for name != "" {
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		} else {
+			name = ""
+		}
+		if s, ok := c.Hosts["*."+name]; ok {
+			return s, true
+		}
+	}
+	return configSection{}, false
+}
+
+// flagGiven reports whether the flag was given on the command line
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+// setFlags sets each flag that wasn't given on the command line. Flags
+// that share a variable, such as -p and -pretty, share a flag.Value, so
+// giving either one keeps the config from setting the other.
+func setFlags(values map[string]interface{}) error {
+	given := make(map[flag.Value]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+	for name, v := range values {
+		switch name {
+		case "config", "url", "method":
+			return fmt.Errorf("%s can't be set in the config", name)
+		}
+		f := flag.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown flag %q", name)
+		}
+		if given[f.Value] {
+			continue
+		}
+		// a list sets a flag that may be repeated, such as -hmac
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		for _, item := range list {
+			var s string
+			switch item := item.(type) {
+			case string:
+				s = item
+			case bool:
+				s = strconv.FormatBool(item)
+			case float64:
+				s = strconv.FormatFloat(item, 'f', -1, 64)
+			default:
+				return fmt.Errorf("flag %q: unsupported value %v", name, item)
+			}
+			if err := f.Value.Set(s); err != nil {
+				return fmt.Errorf("flag %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// configFlags replaces the command line flags with a few, as given by
+// args, restoring them and the config globals when the test is done
+func configFlags(t *testing.T, args ...string) (print *string, pretty *bool, timeout *time.Duration) {
+	saved, savedEnv, savedVars, savedHeaders := flag.CommandLine, environment, variables, configHeaders
+	t.Cleanup(func() {
+		flag.CommandLine, environment, variables, configHeaders = saved, savedEnv, savedVars, savedHeaders
+	})
+	environment, variables, configHeaders = "", nil, make(map[string]string)
+
+	flag.CommandLine = flag.NewFlagSet("gurl", flag.ContinueOnError)
+	print, pretty, timeout = new(string), new(bool), new(time.Duration)
+	flag.StringVar(print, "print", "A", "")
+	flag.BoolVar(pretty, "pretty", true, "")
+	flag.BoolVar(pretty, "p", true, "")
+	flag.DurationVar(timeout, "timeout", time.Minute, "")
+	flag.StringVar(&environment, "env", "", "")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return print, pretty, timeout
+}
+
+func writeConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testConfig = `{
+  "flags": {"print": "hb", "timeout": "5s"},
+  "headers": {"accept": "application/json", "x-team": "core"},
+  "hosts": {
+    "api.example.com": {"flags": {"print": "b", "pretty": false}, "headers": {"X-Team": "api"}},
+    "*.internal.example.com": {"flags": {"timeout": "30s"}}
+  },
+  "environments": {"dev": {"host": "api.example.com"}, "prod": {"host": "prod.example.com"}}
+}`
+
+func TestLoadConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		url         string
+		wantPrint   string
+		wantPretty  bool
+		wantTimeout time.Duration
+		wantHeaders map[string]string
+	}{
+		{name: "top level", url: "example.org", wantPrint: "hb", wantPretty: true, wantTimeout: 5 * time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "core"}},
+		{name: "host", url: "https://api.example.com/users", wantPrint: "b", wantTimeout: 5 * time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "api"}},
+		{name: "wildcard host", url: "db.internal.example.com:8080", wantPrint: "hb", wantPretty: true, wantTimeout: 30 * time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "core"}},
+		{name: "host from the environment", args: []string{"-env=dev"}, url: "{{host}}/users", wantPrint: "b", wantTimeout: 5 * time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "api"}},
+		{name: "flags given win", args: []string{"-print=H", "-p", "-timeout=1s"}, url: "api.example.com",
+			wantPrint: "H", wantPretty: true, wantTimeout: time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "api"}},
+		{name: "flags given by another name win", args: []string{"-p=true"}, url: "api.example.com",
+			wantPrint: "b", wantPretty: true, wantTimeout: 5 * time.Second,
+			wantHeaders: map[string]string{"Accept": "application/json", "X-Team": "api"}},
+	}
+
+	path := writeConfig(t, testConfig)
+	for _, tc := range testCases {
+		print, pretty, timeout := configFlags(t, tc.args...)
+		if err := applyConfig(path, tc.url); err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if *print != tc.wantPrint || *pretty != tc.wantPretty || *timeout != tc.wantTimeout {
+			t.Errorf("%v: print %v pretty %v timeout %v", tc.name, *print, *pretty, *timeout)
+		}
+		if !cmp.Equal(tc.wantHeaders, configHeaders) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.wantHeaders, configHeaders))
+		}
+	}
+}
+
+func TestLoadConfigEnvironment(t *testing.T) {
+	configFlags(t, "-env=prod")
+	if err := applyConfig(writeConfig(t, testConfig), "{{host}}"); err != nil {
+		t.Fatal(err)
+	}
+	if environment != "prod" || variables["host"] != "prod.example.com" {
+		t.Errorf("unexpected environment %v %v", environment, variables)
+	}
+
+	configFlags(t)
+	if err := applyConfig(writeConfig(t, `{"flags": {"env": "dev"}, "environments": {"dev": {}}}`), ""); err != nil {
+		t.Fatal(err)
+	}
+	if environment != "dev" {
+		t.Errorf("environment %q not set by the config", environment)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		name    string
+		args    []string
+		path    string
+		data    string
+		wantErr string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "nope.json"), wantErr: "can't read the config"},
+		{name: "invalid json", data: `{"flags":`, wantErr: "invalid config"},
+		{name: "unknown flag", data: `{"flags": {"nope": 1}}`, wantErr: `unknown flag "nope"`},
+		{name: "flag that can't be set", data: `{"flags": {"url": "x"}}`, wantErr: "url can't be set in the config"},
+		{name: "invalid value", data: `{"flags": {"timeout": "soon"}}`, wantErr: `flag "timeout"`},
+		{name: "unsupported value", data: `{"flags": {"print": {"a": 1}}}`, wantErr: "unsupported value"},
+		{name: "unknown environment", args: []string{"-env=qa"}, data: testConfig, wantErr: `unknown environment "qa" in`},
+		{name: "env per host", data: `{"hosts": {"example.org": {"flags": {"env": "dev"}}}}`, wantErr: "env can't be set per host"},
+	}
+
+	for _, tc := range testCases {
+		configFlags(t, tc.args...)
+		path := tc.path
+		if path == "" {
+			path = writeConfig(t, tc.data)
+		}
+		err := applyConfig(path, "example.org")
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%v: error %v, wanted %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if got, want := defaultConfigPath(), filepath.Join(dir, "gurl", "config.json"); got != want {
+		t.Errorf("default config %v, wanted %v", got, want)
+	}
+
+	// the default config need not exist, unless an environment is asked for
+	configFlags(t)
+	if err := applyConfig("", "example.org"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	configFlags(t, "-env=dev")
+	if err := applyConfig("", "example.org"); err == nil || !strings.Contains(err.Error(), "no environments") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
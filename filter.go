@@ -15,15 +15,7 @@ func filter(args []string) []string {
 	} else if len(args) > 0 && *method == "GET" {
 		for _, v := range args[1:] {
 			// defaults to either GET (with no request data) or POST (with request data).
-			// Params
-			strs := strings.Split(v, "=")
-			if len(strs) == 2 {
-				*method = "POST"
-				break
-			}
-			// files
-			strs = strings.Split(v, "@")
-			if len(strs) == 2 {
+			if it, ok := parseItem(v); ok && it.isData() {
 				*method = "POST"
 				break
 			}
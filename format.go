@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// formatter indents the bodies of a family of media types for pretty
+// printing, and colorizes the indented result for a terminal. Binary
+// formats are first decoded, into JSON, whether pretty printing or not.
+type formatter struct {
+	indent func(body []byte) (string, error)
+	color  func(str string) string
+	decode func(body []byte, contentType string) ([]byte, error)
+}
+
+var (
+	jsonFormatter     = formatter{indentJSON, ColorfulJson, nil}
+	xmlFormatter      = formatter{indentXML, ColorfulXML, nil}
+	htmlFormatter     = formatter{indentHTML, ColorfulXML, nil}
+	yamlFormatter     = formatter{indentYAML, ColorfulYAML, nil}
+	formFormatter     = formatter{indentForm, ColorfulForm, nil}
+	msgpackFormatter  = formatter{indentRecords, ColorfulJson, decodeMsgpack}
+	cborFormatter     = formatter{indentRecords, ColorfulJson, decodeCBOR}
+	protobufFormatter = formatter{indentJSON, ColorfulJson, decodeProtobuf}
+)
+
+// formatters are keyed by media type, and then by structured syntax
+// suffix, so that application/soap+xml is XML, for instance.
+var (
+	formatters = map[string]formatter{
+		"application/json":                  jsonFormatter,
+		"application/problem+json":          jsonFormatter,
+		"application/xml":                   xmlFormatter,
+		"text/xml":                          xmlFormatter,
+		"application/soap+xml":              xmlFormatter,
+		"application/xhtml+xml":             xmlFormatter,
+		"text/html":                         htmlFormatter,
+		"application/yaml":                  yamlFormatter,
+		"application/x-yaml":                yamlFormatter,
+		"text/yaml":                         yamlFormatter,
+		"text/x-yaml":                       yamlFormatter,
+		"application/x-www-form-urlencoded": formFormatter,
+		"application/msgpack":               msgpackFormatter,
+		"application/x-msgpack":             msgpackFormatter,
+		"application/vnd.msgpack":           msgpackFormatter,
+		"application/cbor":                  cborFormatter,
+		"application/cbor-seq":              cborFormatter,
+		"application/x-protobuf":            protobufFormatter,
+		"application/protobuf":              protobufFormatter,
+		"application/vnd.google.protobuf":   protobufFormatter,
+		"application/x-google-protobuf":     protobufFormatter,
+	}
+	suffixFormatters = map[string]formatter{
+		"+json": jsonFormatter,
+		"+xml":  xmlFormatter,
+		"+yaml": yamlFormatter,
+		"+cbor": cborFormatter,
+	}
+)
+
+// formatterFor finds the formatter for a Content-Type, if there is one
+func formatterFor(contentType string) (formatter, bool) {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if f, ok := formatters[mediatype]; ok {
+			return f, true
+		}
+		if i := strings.LastIndexByte(mediatype, '+'); i >= 0 {
+			if f, ok := suffixFormatters[mediatype[i:]]; ok {
+				return f, true
+			}
+		}
+	}
+	// any other application/...json type is still treated as JSON
+	match, err := regexp.MatchString(contentJsonRegex, contentType)
+	if err != nil {
+		log.Fatalln("failed to compile regex", err)
+	}
+	return jsonFormatter, match
+}
+
+func indentJSON(body []byte) (string, error) {
+	var output bytes.Buffer
+	if err := json.Indent(&output, body, "", "  "); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// indentRecords indents each line of decoded JSON records on its own
+func indentRecords(body []byte) (string, error) {
+	var records []string
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		str, err := indentJSON(line)
+		if err != nil {
+			return "", err
+		}
+		records = append(records, str)
+	}
+	return strings.Join(records, "\n"), nil
+}
+
+// indentYAML re-encodes each document, keeping key order and comments
+func indentYAML(body []byte) (string, error) {
+	var output bytes.Buffer
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	enc := yaml.NewEncoder(&output)
+	enc.SetIndent(2)
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if err := enc.Encode(&doc); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(output.String(), "\n"), nil
+}
+
+// indentForm decodes a form, one field per line, keeping field order
+func indentForm(body []byte) (string, error) {
+	var lines []string
+	for _, field := range strings.Split(strings.TrimSpace(string(body)), "&") {
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		for i := range kv {
+			if s, err := url.QueryUnescape(kv[i]); err == nil {
+				kv[i] = s
+			}
+		}
+		lines = append(lines, strings.Join(kv, "="))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// markupPattern matches comments, CDATA sections, declarations and
+// processing instructions, and tags, whose attributes may hold a >.
+var markupPattern = regexp.MustCompile(`<!--[\s\S]*?-->|<!\[CDATA\[[\s\S]*?\]\]>|<[!?][^>]*>|</?[A-Za-z][^\s/>]*(?:\s+[^\s=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>]+))?)*\s*/?>`)
+
+const (
+	markupText = iota
+	markupStart
+	markupEnd
+	markupOther
+	markupRaw
+)
+
+type markupToken struct {
+	kind int
+	name string
+	text string
+}
+
+var (
+	// htmlVoid elements never have an end tag
+	htmlVoid = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true,
+		"hr": true, "img": true, "input": true, "link": true, "meta": true,
+		"param": true, "source": true, "track": true, "wbr": true,
+	}
+	// htmlRaw elements hold text that must not be reindented or parsed
+	htmlRaw = map[string]bool{"script": true, "style": true, "pre": true, "textarea": true}
+	// htmlImplied lists the open elements a start tag implicitly ends
+	htmlImplied = map[string][]string{
+		"li":     {"li"},
+		"dt":     {"dt", "dd"},
+		"dd":     {"dt", "dd"},
+		"tr":     {"tr", "td", "th"},
+		"td":     {"td", "th"},
+		"th":     {"td", "th"},
+		"thead":  {"thead", "tbody", "tr", "td", "th"},
+		"tbody":  {"thead", "tbody", "tr", "td", "th"},
+		"option": {"option"},
+		"p":      {"p"},
+		"div":    {"p"},
+		"ul":     {"p"},
+		"ol":     {"p"},
+		"table":  {"p"},
+		"h1":     {"p"},
+		"h2":     {"p"},
+		"h3":     {"p"},
+		"form":   {"p"},
+	}
+)
+
+// tokenizeMarkup splits XML or HTML into tags, text and everything else.
+// In HTML, the content of raw text elements is kept as a single token.
+func tokenizeMarkup(s string, html bool) []markupToken {
+	var tokens []markupToken
+	for len(s) > 0 {
+		loc := markupPattern.FindStringIndex(s)
+		if loc == nil {
+			tokens = append(tokens, markupToken{kind: markupText, text: s})
+			break
+		}
+		if loc[0] > 0 {
+			tokens = append(tokens, markupToken{kind: markupText, text: s[:loc[0]]})
+		}
+		tag := s[loc[0]:loc[1]]
+		s = s[loc[1]:]
+
+		t := markupToken{kind: markupOther, text: tag}
+		if tag[1] == '/' {
+			t.kind = markupEnd
+			t.name = tagName(tag[2:], html)
+		} else if tag[1] != '!' && tag[1] != '?' {
+			t.kind = markupStart
+			t.name = tagName(tag[1:], html)
+			if strings.HasSuffix(tag, "/>") || (html && htmlVoid[t.name]) {
+				t.kind = markupOther
+			}
+		}
+		tokens = append(tokens, t)
+
+		if html && t.kind == markupStart && htmlRaw[t.name] {
+			end := strings.Index(strings.ToLower(s), "</"+t.name)
+			if end < 0 {
+				end = len(s)
+			}
+			if end > 0 {
+				tokens = append(tokens, markupToken{kind: markupRaw, text: s[:end]})
+			}
+			s = s[end:]
+		}
+	}
+	return tokens
+}
+
+func tagName(s string, html bool) string {
+	if i := strings.IndexAny(s, " \t\r\n/>"); i >= 0 {
+		s = s[:i]
+	}
+	if html {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func indentXML(body []byte) (string, error) {
+	return indentMarkup(tokenizeMarkup(string(body), false), false), nil
+}
+
+func indentHTML(body []byte) (string, error) {
+	return indentMarkup(tokenizeMarkup(string(body), true), true), nil
+}
+
+// indentMarkup puts each element on its own line, indented by depth,
+// except that an element holding only a line of text stays on one line.
+func indentMarkup(tokens []markupToken, html bool) string {
+	var out strings.Builder
+	var open []string
+	write := func(s string) {
+		out.WriteString(strings.Repeat("  ", len(open)))
+		out.WriteString(s)
+		out.WriteByte('\n')
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case markupText:
+			for _, line := range strings.Split(t.text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					write(line)
+				}
+			}
+		case markupRaw:
+			out.WriteString(strings.Trim(t.text, "\r\n"))
+			out.WriteByte('\n')
+		case markupOther:
+			write(t.text)
+		case markupStart:
+			if html {
+				for len(open) > 0 && inSlice(open[len(open)-1], htmlImplied[t.name]) {
+					open = open[:len(open)-1]
+				}
+			}
+			// keep <a>text</a> and <a></a> together
+			if i+1 < len(tokens) && tokens[i+1].kind == markupEnd && tokens[i+1].name == t.name {
+				write(t.text + tokens[i+1].text)
+				i++
+				continue
+			}
+			if i+2 < len(tokens) && tokens[i+1].kind == markupText && tokens[i+2].kind == markupEnd &&
+				tokens[i+2].name == t.name && !strings.Contains(strings.TrimSpace(tokens[i+1].text), "\n") {
+				write(t.text + strings.TrimSpace(tokens[i+1].text) + tokens[i+2].text)
+				i += 2
+				continue
+			}
+			write(t.text)
+			open = append(open, t.name)
+		case markupEnd:
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == t.name {
+					open = open[:j]
+					break
+				}
+			}
+			write(t.text)
+		}
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
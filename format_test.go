@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatterFor(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		contentType string
+		want        formatter
+		wantOk      bool
+	}{
+		{contentType: "application/json", want: jsonFormatter, wantOk: true},
+		{contentType: "application/json; charset=utf-8", want: jsonFormatter, wantOk: true},
+		{contentType: "application/vnd.api+json", want: jsonFormatter, wantOk: true},
+		{contentType: "text/xml", want: xmlFormatter, wantOk: true},
+		{contentType: "application/atom+xml", want: xmlFormatter, wantOk: true},
+		{contentType: "text/html; charset=utf-8", want: htmlFormatter, wantOk: true},
+		{contentType: "application/x-yaml", want: yamlFormatter, wantOk: true},
+		{contentType: "application/openapi+yaml", want: yamlFormatter, wantOk: true},
+		{contentType: "application/x-www-form-urlencoded", want: formFormatter, wantOk: true},
+		{contentType: "application/vnd.msgpack", want: msgpackFormatter, wantOk: true},
+		{contentType: "application/senml+cbor", want: cborFormatter, wantOk: true},
+		{contentType: "application/x-protobuf", want: protobufFormatter, wantOk: true},
+		{contentType: "text/plain"},
+		{contentType: "image/png"},
+	}
+
+	// formatters hold functions, which are the same if they are at the
+	// same address
+	same := func(a, b interface{}) bool {
+		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		if va.IsNil() || vb.IsNil() {
+			return va.IsNil() == vb.IsNil()
+		}
+		return va.Pointer() == vb.Pointer()
+	}
+	for _, tc := range testCases {
+		got, ok := formatterFor(tc.contentType)
+		if ok != tc.wantOk {
+			t.Errorf("%v: found %v, wanted %v", tc.contentType, ok, tc.wantOk)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !same(got.indent, tc.want.indent) || !same(got.decode, tc.want.decode) {
+			t.Errorf("%v: unexpected formatter", tc.contentType)
+		}
+	}
+}
+
+func TestIndent(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		indent  func([]byte) (string, error)
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "xml",
+			indent: indentXML,
+			input:  `<?xml version="1.0"?><a><b x="1>2">text</b><c/><d></d></a>`,
+			want:   "<?xml version=\"1.0\"?>\n<a>\n  <b x=\"1>2\">text</b>\n  <c/>\n  <d></d>\n</a>",
+		},
+		{
+			name:   "xml comments and cdata",
+			indent: indentXML,
+			input:  "<a><!-- <b> --><![CDATA[<c>]]></a>",
+			want:   "<a>\n  <!-- <b> -->\n  <![CDATA[<c>]]>\n</a>",
+		},
+		{
+			name:   "xml text on several lines",
+			indent: indentXML,
+			input:  "<a>one\n  two</a>",
+			want:   "<a>\n  one\n  two\n</a>",
+		},
+		{
+			name:   "html void and implied end tags",
+			indent: indentHTML,
+			input:  "<UL><li>one<li>two<br></UL>",
+			want:   "<UL>\n  <li>\n    one\n  <li>\n    two\n    <br>\n</UL>",
+		},
+		{
+			name:   "html raw text",
+			indent: indentHTML,
+			input:  "<div><script>if (a < b) { x() }</script></div>",
+			want:   "<div>\n  <script>\nif (a < b) { x() }\n  </script>\n</div>",
+		},
+		{
+			name:   "yaml",
+			indent: indentYAML,
+			input:  "b: 1\na:\n    - x # note\n",
+			want:   "b: 1\na:\n  - x # note",
+		},
+		{
+			name:   "yaml documents",
+			indent: indentYAML,
+			input:  "a: 1\n---\nb: 2\n",
+			want:   "a: 1\n---\nb: 2",
+		},
+		{
+			name:    "invalid yaml",
+			indent:  indentYAML,
+			input:   "a: [",
+			wantErr: true,
+		},
+		{
+			name:   "form",
+			indent: indentForm,
+			input:  "b=2&a=x+y%26z&&flag\n",
+			want:   "b=2\na=x y&z\nflag",
+		},
+		{
+			name:   "records",
+			indent: indentRecords,
+			input:  "{\"a\":1}\n[]",
+			want:   "{\n  \"a\": 1\n}\n[]",
+		},
+		{
+			name:    "invalid record",
+			indent:  indentRecords,
+			input:   "{\"a\":1}\n{",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := tc.indent([]byte(tc.input))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%v: got %q, wanted %q", tc.name, got, tc.want)
+		}
+	}
+}
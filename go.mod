@@ -1,5 +1,21 @@
 module github.com/skunkwerks/gurl
 
-go 1.15
+go 1.17
 
-require github.com/google/go-cmp v0.5.4
+require (
+	github.com/andybalholm/brotli v1.0.6
+	github.com/fxamacker/cbor/v2 v2.5.0
+	github.com/google/go-cmp v0.5.5
+	github.com/gorilla/websocket v1.5.0
+	github.com/klauspost/compress v1.14.4
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
+	google.golang.org/protobuf v1.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+)
@@ -18,7 +18,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"flag"
 	"fmt"
 	"io"
@@ -27,11 +26,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/skunkwerks/gurl/hamac"
+	"github.com/skunkwerks/gurl/httplib"
 )
 
 const (
@@ -48,6 +48,12 @@ var (
 	pretty           bool
 	download         bool
 	insecureSSL      bool
+	certFile         string
+	certKeyFile      string
+	caFile           string
+	timeout          time.Duration
+	configPath       string
+	environment      string
 	auth             string
 	proxy            string
 	printV           string
@@ -56,19 +62,53 @@ var (
 	bench            bool
 	benchN           int
 	benchC           int
-	hmacEnv          string
+	hmacEnvs         stringList
+	hmacVerify       bool
+	hmacTimestamp    int64
+	hmacRef          bool
+	hmacCmd          bool
+	sigv4            string
+	sigv4Profile     string
+	sigv4Unsigned    bool
+	sseReconnect     bool
+	stream           bool
+	wsPing           time.Duration
+	rawEncoding      bool
+	compress         string
+	bodyFormat       string
+	protoFile        string
+	protoMessage     string
+	style            string
+	colorMode        string
+	sigKey           string
+	sigAlg           string
+	sigKeyID         string
+	sigLabel         string
+	sigComponents    string
+	sigExpires       time.Duration
+	listen           string
+	listenStatus     int
+	listenBody       string
+	listenHeaders    stringList
+	mock             string
+	offline          bool
+	offlineOut       string
+	multipartType    string
+	stdin            io.Reader
+	ignoreStdin      bool
+	uploading        bool
 	isjson           = flag.Bool("json", true, "Send the data as a JSON object")
 	method           = flag.String("method", "GET", "HTTP method")
 	URL              = flag.String("url", "", "HTTP request URL")
-	jsonmap          map[string]interface{}
+	jsonItems        interface{}
 	contentJsonRegex = `application/(.*)json`
 )
 
 func init() {
 	flag.BoolVar(&ver, "v", false, "Print Version Number")
 	flag.BoolVar(&ver, "version", false, "Print Version Number")
-	flag.BoolVar(&pretty, "pretty", true, "Print JSON Pretty Format")
-	flag.BoolVar(&pretty, "p", true, "Print JSON Pretty Format")
+	flag.BoolVar(&pretty, "pretty", true, "Print JSON, XML, HTML, YAML and forms Pretty Format")
+	flag.BoolVar(&pretty, "p", true, "Print JSON, XML, HTML, YAML and forms Pretty Format")
 	flag.StringVar(&printV, "print", "A", "Print request and response")
 	flag.BoolVar(&form, "form", false, "Submitting as a form")
 	flag.BoolVar(&form, "f", false, "Submitting as a form")
@@ -76,6 +116,12 @@ func init() {
 	flag.BoolVar(&download, "d", false, "Download the url content as file")
 	flag.BoolVar(&insecureSSL, "insecure", false, "Allow connections to SSL sites without certs")
 	flag.BoolVar(&insecureSSL, "i", false, "Allow connections to SSL sites without certs")
+	flag.StringVar(&certFile, "cert", "", "PEM client certificate, and its key unless -cert.key is given")
+	flag.StringVar(&certKeyFile, "cert.key", "", "PEM key of the -cert client certificate")
+	flag.StringVar(&caFile, "ca", "", "PEM CA certificates to trust, besides the system ones")
+	flag.DurationVar(&timeout, "timeout", 60*time.Second, "Timeout to connect, and to read and write the connection")
+	flag.StringVar(&configPath, "config", "", "Config file, default ~/.config/gurl/config.json")
+	flag.StringVar(&environment, "env", "", "Environment of the config whose {{variables}} requests use")
 	flag.StringVar(&auth, "auth", "", "HTTP authentication username:password, USER[:PASS]")
 	flag.StringVar(&auth, "a", "", "HTTP authentication username:password, USER[:PASS]")
 	flag.StringVar(&proxy, "proxy", "", "Proxy host and port, PROXY_URL")
@@ -84,8 +130,61 @@ func init() {
 	flag.IntVar(&benchN, "b.N", 1000, "Number of requests to run")
 	flag.IntVar(&benchC, "b.C", 100, "Number of requests to run concurrently.")
 	flag.StringVar(&body, "body", "", "Raw data send as body")
-	flag.StringVar(&hmacEnv, "hmac", "", "name of env var to retrieve HMAC details")
-	jsonmap = make(map[string]interface{})
+	flag.BoolVar(&ignoreStdin, "ignore-stdin", false, "Don't read stdin, even when it is a pipe or file")
+	flag.Var(&hmacEnvs, "hmac", "name of env var to retrieve HMAC details, repeat to rotate keys")
+	flag.BoolVar(&hmacVerify, "hmac.verify", false, "Fail unless the response carries a matching HMAC signature")
+	flag.Int64Var(&hmacTimestamp, "hmac.timestamp", 0, "Unix time to sign with for stripe and slack presets, default now")
+	flag.BoolVar(&hmacRef, "hmac.ref", false, "Read HMAC secrets given as file:, fd:, env:, base64:, hex: or raw: references")
+	flag.BoolVar(&hmacCmd, "hmac.cmd", false, "Also run the command of cmd: HMAC secrets, implies -hmac.ref")
+	flag.StringVar(&sigv4, "sigv4", "", "Sign with AWS SigV4, REGION:SERVICE")
+	flag.StringVar(&sigv4Profile, "sigv4.profile", "", "AWS credentials profile for SigV4")
+	flag.BoolVar(&sigv4Unsigned, "sigv4.unsigned", false, "Sign with UNSIGNED-PAYLOAD instead of the body hash")
+	flag.StringVar(&sigKey, "sig.key", "", "PEM key file for HTTP Message Signatures")
+	flag.StringVar(&sigAlg, "sig.alg", "", "HTTP Message Signature algorithm, inferred from the key if empty")
+	flag.StringVar(&sigKeyID, "sig.keyid", "", "HTTP Message Signature keyid parameter")
+	flag.StringVar(&sigLabel, "sig.label", "sig1", "HTTP Message Signature label")
+	flag.StringVar(&sigComponents, "sig.components", "", "Components covered by the HTTP Message Signature, default @method @target-uri and the content")
+	flag.DurationVar(&sigExpires, "sig.expires", 0, "HTTP Message Signature lifetime, for the expires parameter")
+	flag.StringVar(&listen, "listen", "", "Run a server on ADDR, printing every request it receives")
+	flag.IntVar(&listenStatus, "listen.status", 200, "Status code the -listen server replies with")
+	flag.StringVar(&listenBody, "listen.body", "", "Body the -listen server replies with, or @file")
+	flag.Var(&listenHeaders, "listen.header", "Header the -listen server replies with, Name:Value, may be repeated")
+	flag.BoolVar(&rawEncoding, "raw-encoding", false, "Keep the response body as it was encoded by the server")
+	flag.StringVar(&compress, "compress", "", "Compress the request body with gzip, deflate, br or zstd")
+	flag.StringVar(&bodyFormat, "format", "json", "Encode request items as json, msgpack, cbor or protobuf")
+	flag.StringVar(&protoFile, "proto", "", "Protobuf descriptor set or .proto file, to decode and encode messages")
+	flag.StringVar(&protoMessage, "proto.message", "", "Full name of the protobuf message type")
+	flag.StringVar(&colorMode, "color", "auto", "Color output: auto, always or never")
+	flag.StringVar(&style, "style", "default", "Color theme: default, monokai, solarized, dracula, mono or none")
+	flag.BoolVar(&stream, "stream", false, "Print the response body as it arrives")
+	flag.DurationVar(&wsPing, "ws.ping", 0, "Interval between WebSocket pings, none if 0")
+	flag.BoolVar(&sseReconnect, "sse.reconnect", true, "Reconnect with Last-Event-ID when an event stream closes")
+	flag.StringVar(&mock, "mock", "", "Serve canned responses from a route file, HAR or JSONL recording")
+	flag.BoolVar(&offline, "offline", false, "Build and print the request without sending it")
+	flag.StringVar(&offlineOut, "offline.out", "", "Write the -offline request to FILE instead")
+	flag.StringVar(&multipartType, "multipart", "", "Send a multipart body: form-data, mixed or related")
+}
+
+// loadHmacs parses the details in each -hmac env var, in order, so the
+// first signs and all of them are accepted when verifying.
+func loadHmacs() []hamac.Hmac {
+	var macs []hamac.Hmac
+	for _, env := range hmacEnvs {
+		parse := hamac.Parse
+		if hmacRef || hmacCmd {
+			parse = func(input string) (hamac.Hmac, error) {
+				return hamac.ParseRef(input, hmacCmd)
+			}
+		}
+		mac, err := parse(os.Getenv(env))
+		if err == hamac.ErrSecretCmd {
+			log.Fatalf("HMAC details from %s: %v, use -hmac.cmd to run it", env, err)
+		} else if err != nil {
+			log.Fatalf("HMAC details from %s: %v", env, err)
+		}
+		macs = append(macs, mac)
+	}
+	return macs
 }
 
 func parsePrintOption(s string) {
@@ -114,54 +213,67 @@ func main() {
 	flag.Usage = usage
 	flag.Parse()
 	args := flag.Args()
+	if ver {
+		fmt.Println("Version:", version)
+		os.Exit(2)
+	}
 
 	if len(args) > 0 {
 		args = filter(args)
 	}
-
-	if ver {
-		fmt.Println("Version:", version)
-		os.Exit(2)
+	// the config sets defaults for any flag not given above
+	loadConfig(configPath, *URL)
+	var err error
+	if *URL, err = expandVars(*URL); err != nil {
+		log.Fatal("URL: ", err)
+	}
+	if body, err = expandVars(body); err != nil {
+		log.Fatal("-body: ", err)
 	}
 
 	parsePrintOption(printV)
+	setupOutput(colorMode, style)
 	if printOption&printReqBody != printReqBody {
 		defaultSetting.DumpBody = false
 	}
+	if rawEncoding {
+		defaultSetting.Gzip = false
+	}
+	defaultSetting.ConnectTimeout = timeout
+	defaultSetting.ReadWriteTimeout = timeout
+	if _, ok := bodyFormats[bodyFormat]; !ok {
+		log.Fatalf("unsupported body format %q, use json, msgpack, cbor or protobuf", bodyFormat)
+	}
+	if multipartType != "" && !inSlice(multipartType, []string{"form-data", "mixed", "related"}) {
+		log.Fatalf("unsupported multipart type %q, use form-data, mixed or related", multipartType)
+	}
 
-	// read stdin into memory in single pass
-	var stdin []byte
+	if hmacVerify && len(hmacEnvs) == 0 {
+		log.Fatal("-hmac.verify needs HMAC details from -hmac")
+	}
 
-	if runtime.GOOS != "windows" {
-		fi, err := os.Stdin.Stat()
-		if err != nil {
-			panic(err)
-		}
-		if fi.Size() != 0 {
-			stdin, err = io.ReadAll(os.Stdin)
-			if err != nil {
-				log.Fatal("Read from Stdin", err)
-			}
+	// inspect incoming requests, instead of sending one
+	if mock != "" {
+		if listen == "" {
+			listen = ":8080"
 		}
+		serve(listen, loadMock(mock))
+		return
+	}
+	if listen != "" {
+		serve(listen, cannedResponse(listenStatus, listenHeaders, listenBody))
+		return
+	}
+
+	// stdin is streamed as the body, rather than read into memory
+	if !ignoreStdin {
+		stdin = stdinBody()
 	}
 
 	if *URL == "" {
 		usage()
 	}
-	if strings.HasPrefix(*URL, ":") {
-		urlb := []byte(*URL)
-		if *URL == ":" {
-			*URL = "http://localhost/"
-		} else if len(*URL) > 1 && urlb[1] != '/' {
-			*URL = "http://localhost" + *URL
-		} else {
-			*URL = "http://localhost" + string(urlb[1:])
-		}
-	}
-	if !strings.HasPrefix(*URL, "http://") && !strings.HasPrefix(*URL, "https://") {
-		*URL = "http://" + *URL
-	}
-	u, err := url.Parse(*URL)
+	u, err := url.Parse(expandURL(*URL))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -179,9 +291,9 @@ func main() {
 		password, _ := u.User.Password()
 		httpreq.GetRequest().SetBasicAuth(u.User.Username(), password)
 	}
-	// Insecure SSL Support
-	if insecureSSL {
-		httpreq.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	// TLS client certificates, CAs and insecure SSL support
+	if c := clientTLSConfig(); c != nil {
+		httpreq.SetTLSClientConfig(c)
 	}
 	// Proxy Support
 	if proxy != "" {
@@ -198,33 +310,130 @@ func main() {
 		httpreq.SetProxy(http.ProxyURL(eurl))
 	}
 
-	// set body if supplied, or via stdin
-	if body != "" {
+	// set body if supplied, or via stdin, which a WebSocket sends as messages
+	ws := isWebsocket(u.Scheme)
+	// -hmac signs a body, which the handshake doesn't have, while
+	// messages have no headers to carry a signature
+	if ws && len(hmacEnvs) > 0 {
+		log.Fatal("-hmac can't sign a WebSocket handshake or its messages, use -sig.key or -sigv4 to sign the handshake")
+	}
+	if body != "" && stdin != nil && !ws {
+		log.Fatal("-body and stdin can't both be sent as the body, use -ignore-stdin to leave stdin out")
+	}
+	if body != "" && !ws {
 		httpreq.Body(body)
 	}
-	if len(stdin) > 0 {
+	if stdin != nil && !ws {
 		httpreq.Body(stdin)
+		uploading = true
+	}
+	if compress != "" {
+		httpreq.Compress(compress)
 	}
 
 	// request body has now been finalised
-	// If HMAC was requested, sign body, & wrap signature as envelope
-	mac := hamac.New(os.Getenv(hmacEnv))
-	if mac.Enabled {
-		httpreq.SignBody(mac)
+	// If HMAC was requested, sign body with the newest key, & wrap
+	// signature as envelope
+	macs := loadHmacs()
+	if len(macs) > 0 {
+		signedAt := time.Now()
+		if hmacTimestamp != 0 {
+			signedAt = time.Unix(hmacTimestamp, 0)
+		}
+		if err := httpreq.SignBodyAt(macs[0], signedAt); err != nil {
+			log.Fatal("can't sign the body ", err)
+		}
+	}
+
+	// SigV4 is applied as the request is sent, as it covers the final URL
+	if sigv4 != "" {
+		v, err := hamac.NewSigV4(sigv4, sigv4Profile)
+		if err != nil {
+			log.Fatal("SigV4 credentials: ", err)
+		}
+		v.Unsigned = sigv4Unsigned
+		httpreq.SignV4(v)
+	}
+
+	// RFC 9421 signatures also cover the final URL, and are applied last
+	if sigKey != "" {
+		s, err := hamac.NewMessageSigner(sigAlg, sigKey)
+		if err != nil {
+			log.Fatal("HTTP Message Signature key: ", err)
+		}
+		s.KeyID = sigKeyID
+		s.Label = sigLabel
+		s.Components = strings.Fields(sigComponents)
+		s.Expires = sigExpires
+		httpreq.SignMessage(s)
+	}
+
+	if offline {
+		if ws {
+			log.Fatal("-offline can't build a WebSocket handshake")
+		}
+		printOffline(httpreq)
+		return
+	}
+
+	if ws {
+		var input io.Reader = os.Stdin
+		if stdin != nil {
+			input = stdin
+		} else if ignoreStdin {
+			input = strings.NewReader("")
+		}
+		if body != "" {
+			input = io.MultiReader(strings.NewReader(body+"\n"), input)
+		}
+		websocketSession(httpreq, input)
+		return
 	}
 
 	// AB bench
 	if bench {
+		// each request sends the body again, which a pipe can't
+		if ok, err := httpreq.Resendable(); err != nil {
+			log.Fatal(err)
+		} else if !ok {
+			log.Fatal("-bench can't send piped input more than once, redirect a file instead")
+		}
 		httpreq.Debug(false)
 		RunBench(httpreq)
 		return
 	}
 
+	var upload *ProgressBar
+	if uploading && interactive {
+		httpreq.UploadProgress(func(total int64) io.Writer {
+			if total >= 0 && total < uploadProgressMin {
+				return io.Discard
+			}
+			if total < 0 {
+				total = 0
+			}
+			upload = NewProgressBar(total)
+			upload.Start()
+			return upload
+		})
+	}
+
 	res, err := httpreq.Response()
+	if upload != nil {
+		upload.Finish()
+		fmt.Println("")
+	}
 	if err != nil {
 		log.Fatalln("can't get the url", err)
 	}
 
+	// the response is signed with the same envelope and secret
+	if hmacVerify {
+		if err := verifyResponse(res, macs); err != nil {
+			log.Fatalf("response %s: %v", macs[0].Header, err)
+		}
+	}
+
 	// download file
 	if download {
 		var fl string
@@ -249,18 +458,7 @@ func main() {
 		if err != nil {
 			log.Fatal("can't create file", err)
 		}
-		if runtime.GOOS != "windows" {
-			fmt.Println(Color(res.Proto, Magenta), Color(res.Status, Green))
-			for k, v := range res.Header {
-				fmt.Println(Color(k, Gray), ":", Color(strings.Join(v, " "), Cyan))
-			}
-		} else {
-			fmt.Println(res.Proto, res.Status)
-			for k, v := range res.Header {
-				fmt.Println(k, ":", strings.Join(v, " "))
-			}
-		}
-		fmt.Println("")
+		printResponseHeader(res)
 		contentLength := res.Header.Get("Content-Length")
 		var total int64
 		if contentLength != "" {
@@ -269,8 +467,15 @@ func main() {
 		fmt.Printf("Downloading to \"%s\"\n", fl)
 		pb := NewProgressBar(total)
 		pb.Start()
-		multiWriter := io.MultiWriter(fd, pb)
-		_, err = io.Copy(multiWriter, res.Body)
+		// the progress bar counts bytes as received, before decoding
+		var body io.Reader = io.TeeReader(res.Body, pb)
+		if !rawEncoding {
+			body, err = httplib.Decode(body, res.Header.Values("Content-Encoding"))
+			if err != nil {
+				log.Fatal("can't decode response ", err)
+			}
+		}
+		_, err = io.Copy(fd, body)
 		if err != nil {
 			log.Fatal("Can't Write the body into file", err)
 		}
@@ -280,81 +485,7 @@ func main() {
 		return
 	}
 
-	if runtime.GOOS != "windows" {
-		fi, err := os.Stdout.Stat()
-		if err != nil {
-			panic(err)
-		}
-		if fi.Mode()&os.ModeDevice == os.ModeDevice {
-			var dumpHeader, dumpBody []byte
-			dump := httpreq.DumpRequest()
-			dps := strings.Split(string(dump), "\n")
-			for i, line := range dps {
-				if len(strings.Trim(line, "\r\n ")) == 0 {
-					dumpHeader = []byte(strings.Join(dps[:i], "\n"))
-					dumpBody = []byte(strings.Join(dps[i:], "\n"))
-					break
-				}
-			}
-			if printOption&printReqHeader == printReqHeader {
-				fmt.Println(ColorfulRequest(string(dumpHeader)))
-				fmt.Println("")
-			}
-			if printOption&printReqBody == printReqBody {
-				if string(dumpBody) != "\r\n" {
-					fmt.Println(string(dumpBody))
-					fmt.Println("")
-				}
-			}
-			if printOption&printRespHeader == printRespHeader {
-				fmt.Println(Color(res.Proto, Magenta), Color(res.Status, Green))
-				for k, v := range res.Header {
-					fmt.Println(Color(k, Gray), ":", Color(strings.Join(v, " "), Cyan))
-				}
-				fmt.Println("")
-			}
-			if printOption&printRespBody == printRespBody {
-				body := formatResponseBody(res, httpreq, pretty)
-				fmt.Println(ColorfulResponse(body, res.Header.Get("Content-Type")))
-			}
-		} else {
-			body := formatResponseBody(res, httpreq, pretty)
-			_, err = os.Stdout.WriteString(body)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-	} else {
-		var dumpHeader, dumpBody []byte
-		dump := httpreq.DumpRequest()
-		dps := strings.Split(string(dump), "\n")
-		for i, line := range dps {
-			if len(strings.Trim(line, "\r\n ")) == 0 {
-				dumpHeader = []byte(strings.Join(dps[:i], "\n"))
-				dumpBody = []byte(strings.Join(dps[i:], "\n"))
-				break
-			}
-		}
-		if printOption&printReqHeader == printReqHeader {
-			fmt.Println(string(dumpHeader))
-			fmt.Println("")
-		}
-		if printOption&printReqBody == printReqBody {
-			fmt.Println(string(dumpBody))
-			fmt.Println("")
-		}
-		if printOption&printRespHeader == printRespHeader {
-			fmt.Println(res.Proto, res.Status)
-			for k, v := range res.Header {
-				fmt.Println(k, ":", strings.Join(v, " "))
-			}
-			fmt.Println("")
-		}
-		if printOption&printRespBody == printRespBody {
-			body := formatResponseBody(res, httpreq, pretty)
-			fmt.Println(body)
-		}
-	}
+	printExchange(httpreq, res)
 }
 
 var usageinfo string = `gurl is a Go implemented CLI cURL-like tool for humans,
@@ -372,11 +503,39 @@ flags:
   -b.N=1000                   Number of requests to run
   -b.C=100                    Number of requests to run concurrently
   -body=""                    Send RAW data as body
+  -ignore-stdin=false         Don't read stdin as the body, for scripts and cron jobs
   -f, -form=false             Submitting the data as a form
   -j, -json=true              Send the data in a JSON object as application/json
-  -hmac=HMAC_ENV_VAR          Environment variable to fetch HMAC details from
-  -p, -pretty=true            Print JSON Pretty Format
+  -hmac=HMAC_ENV_VAR          Environment variable to fetch HMAC details from,
+                              repeat to accept older keys when verifying
+  -hmac.verify=false          Fail unless the response HMAC header matches
+  -hmac.timestamp=UNIX_TIME   Time signed by the stripe and slack presets
+  -hmac.ref=false             Read HMAC secrets from references, such as file:PATH
+  -hmac.cmd=false             Also allow cmd:COMMAND secrets, which run the command
+  -sigv4=[REGION:]SERVICE     Sign the request with AWS Signature Version 4
+  -sigv4.profile=PROFILE      AWS credentials profile to sign with
+  -sigv4.unsigned=false       Sign with UNSIGNED-PAYLOAD instead of body hash
+  -sig.key=KEY_FILE           Add HTTP Message Signatures, using a PEM key
+  -sig.alg=ALGORITHM          ed25519, ecdsa-p256-sha256, rsa-pss-sha512,
+                              rsa-v1_5-sha256 or hmac-sha256
+  -sig.keyid=KEY_ID           Key identifier sent in the keyid parameter
+  -sig.label=sig1             Label for the Signature-Input and Signature
+  -sig.components="..."       Space separated components to sign, default
+                              "@method @target-uri", with content-digest
+                              and content-type for a body and content type
+  -sig.expires=DURATION       Add an expires parameter, such as 5m
+  -p, -pretty=true            Print JSON, XML, HTML, YAML and forms indented
   -i, -insecure=false         Allow connections to SSL sites without certs
+  -cert=CERT_FILE             PEM client certificate, with its key unless
+                              -cert.key is given
+  -cert.key=KEY_FILE          PEM key of the client certificate
+  -ca=CA_FILE                 PEM CA certificates to trust, as well as the
+                              system ones
+  -timeout=60s                Timeout to connect, and to read and write
+  -config=FILE                JSON config of default flags and headers, per
+                              host, default ~/.config/gurl/config.json
+  -env=NAME                   Environment of the config that defines the
+                              {{variables}} used in the URL and items
   -proxy=PROXY_URL            Proxy with host and port
   -print="..."                String specifying what the output should
                               contain, default will print all information.
@@ -385,6 +544,35 @@ flags:
          "B" request body
          "h" response headers
          "b" response body
+  -listen=ADDR                Run a server on ADDR, such as :8080, printing
+                              each request received, instead of sending one
+  -listen.status=200          Status code the server replies with
+  -listen.body=BODY           Body the server replies with, or @file
+  -listen.header=NAME:VALUE   Header the server replies with, may be repeated
+  -mock=FILE                  Run a mock server on -listen, default :8080,
+                              serving routes from a YAML or JSON route file,
+                              or a HAR or JSONL recording
+  -raw-encoding=false         Keep the response body compressed, as sent
+  -compress=ENCODING          Compress the request body with gzip, deflate,
+                              br or zstd, and set Content-Encoding
+  -format=json                Encode request items as json, msgpack, cbor
+                              or protobuf
+  -proto=FILE                 Protobuf descriptor set, or .proto file
+                              compiled with protoc, to decode responses
+  -proto.message=NAME         Protobuf message type, such as pkg.Message
+  -color=auto                 Color output when it is a terminal, always,
+                              such as for less -R, or never
+  -style=default              Color theme: default, monokai (256 colors),
+                              solarized or dracula (truecolor), mono or none
+  -stream=false               Print the response body as it arrives, one
+                              record at a time for NDJSON and JSON sequences
+  -ws.ping=0s                 Interval to ping a WebSocket server, if set
+  -sse.reconnect=true         Reconnect when an event stream closes
+  -offline=false              Build the request, with its signatures, and
+                              print it as it would be sent, without sending
+  -offline.out=FILE           Write the -offline request to FILE instead
+  -multipart=TYPE             Send fields, files and JSON items as a
+                              multipart form-data, mixed or related body
   -v, -version=true           Show Version Number
 
 METHOD:
@@ -407,13 +595,120 @@ HMAC:
   sha256:x-my-signature:very_secret
   sha1:x-most-wanted-header:bonnie_and_clyde
 
+  Webhook providers' schemes are reproduced by presets, which only need
+  the secret: github, stripe, slack and shopify.
+
+  github:webhook_secret
+  stripe:whsec_1234
+
+  The secret may contain : and is used as it is. With -hmac.ref, it may
+  be read from elsewhere, instead of sitting in the environment, with
+  file:PATH, fd:N or env:VAR, and with -hmac.cmd, from cmd:COMMAND.
+  Encoded secrets are decoded with base64: or hex:, which may wrap the
+  other sources. raw: uses the rest literally.
+
+  -hmac.ref: sha256:x-my-signature:file:/run/secrets/hmac
+  -hmac.cmd: github:base64:cmd:pass show webhooks/github
+
+  With -hmac.verify, the response must carry the same header, signed over
+  the response body with the same secret, or gurl exits with an error.
+  During a key rotation, give -hmac once for the new key, which signs,
+  and again for each old key, which is still accepted when verifying.
+
+LISTEN:
+  gurl -listen=:8080 runs a local server, which prints every request it
+  receives, with the same formatting as outgoing requests, and replies
+  with a canned response. This helps when developing webhook senders.
+
+  With -hmac, the signature of each request is checked and reported, and
+  with -hmac.verify, requests without a valid signature get a 401.
+
+  gurl -listen=:8080 -hmac=HMAC -listen.status=202 -listen.body='{"ok":true}' \
+    -listen.header=Content-Type:application/json
+
+MOCK:
+  gurl -mock=routes.yaml serves canned responses, matched by method and
+  path, where {name} matches one path segment and a final * the rest,
+  and by any query in the path, such as /search?q=go.
+  Bodies are Go templates, with .Method, .Path, .Params, .Query,
+  .Headers, .Body and .JSON from the request. Recordings from a .har or
+  .jsonl file are replayed as they are. Use -print= to serve quietly.
+
+  - method: GET
+    path: /users/{id}
+    status: 200
+    headers:
+      Content-Type: application/json
+    body: '{"id": "{{.Params.id}}"}'
+    delay: 150ms
+
+BINARY FORMATS:
+  MessagePack, CBOR and Protobuf responses are decoded and printed as
+  JSON. Protobuf messages are decoded by field number, unless -proto
+  gives the descriptors, and -proto.message, or the proto parameter of
+  the Content-Type, names the message type. -format sends request items
+  in the same formats.
+
+  gurl -format=msgpack POST example.org/items name=gurl count:=3
+  gurl -proto=api.protoset -proto.message=api.Item example.org/items/1
+
+WEBSOCKET:
+  A ws:// or wss:// URL opens a WebSocket, with the same header, query,
+  auth, TLS and proxy options as any request, and the handshake can be
+  signed with -sig.key or -sigv4, but not -hmac. Each line of stdin,
+  or of -body, is sent as a text message, and each message received is
+  printed, JSON pretty printed on a terminal. Pings are answered, and
+  the connection is closed cleanly at the end of input or on Ctrl-C.
+
+  gurl wss://stream.example.org/feed Authorization:'Bearer xyz'
+  echo '{"subscribe":"prices"}' | gurl -ws.ping=30s wss://example.org/ws
+
+STREAMING:
+  By default, the response body is read in full before it is printed.
+  With -stream, it is printed as it arrives, for long-lived or huge
+  responses. application/x-ndjson and application/json-seq responses
+  are pretty printed a record at a time on a terminal, and passed on
+  unchanged otherwise.
+
+  gurl -stream -print=b logs.example.org/tail
+
+SERVER-SENT EVENTS:
+  A text/event-stream response is printed event by event, as it arrives,
+  with JSON data pretty printed on a terminal. When the server closes
+  the stream, gurl waits for the retry delay, 3s unless the server sets
+  one, and reconnects with Last-Event-ID, until the server replies with
+  anything else, such as 204 No Content. Use -sse.reconnect=false to
+  stop at the end of the first stream.
+
+HTTP MESSAGE SIGNATURES:
+  gurl can sign requests as described in RFC 9421, adding Signature-Input
+  and Signature headers. Covered components may be derived, such as
+  @method, @target-uri, @authority, @scheme, @request-target, @path and
+  @query, or any header name. A Content-Digest header is added when it is
+  covered. The key file is PEM encoded, or holds the shared secret for
+  hmac-sha256.
+
+  gurl -sig.key=ed25519.pem -sig.keyid=partner-1 POST example.org a=b
+
+SIGV4:
+  gurl can sign requests with AWS Signature Version 4, for S3-compatible
+  stores and API Gateway. Credentials are read from AWS_ACCESS_KEY_ID,
+  AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, or from a profile in the
+  shared credentials file. The region defaults to AWS_REGION.
+
+  gurl -sigv4=us-east-1:s3 PUT localhost:9000/bucket/key < file
+
 ITEM:
   Can be any of:
-    Query string   key=value
-    Header         key:value
+    Query string   key==value, or key=value for GET
+    Header         key:value, key:@file, key; for an empty value,
+                   or key: to remove it
     Post data      key=value
     JSON data      key:=value
-    File upload    key@/path/file
+    File upload    key@/path/file, key@/path/file;type=TYPE;filename=NAME,
+                   or key@- to read stdin
+    Nested JSON    key[field]=value, key[]=value, key[0][field]:=value,
+                   or []=value for an array body
 
 Example:
 
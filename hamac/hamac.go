@@ -6,9 +6,13 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"hash"
+	"net/http"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // restricted set of algorithms
@@ -23,63 +27,144 @@ const (
 // struct to hold validated hmac parameters and an overall enabled flag
 type Hmac struct {
 	Enabled   bool
+	Scheme    Scheme
 	Algorithm Algorithm
 	Header    string
 	Secret    string
 }
 
+// errors returned when parsing details, or verifying signatures
+var (
+	ErrNoSignature = errors.New("no signature header found")
+	ErrMismatch    = errors.New("signature does not match")
+	ErrNoDetails   = errors.New("no HMAC details given")
+	ErrEmptySecret = errors.New("HMAC secret is empty")
+)
+
 // used to check if slice only contains legitimate RFC-style characters
 var validHeader = regexp.MustCompile(`(?i)^x-[a-z0-9_-]+$`)
 
-// New hmac will be enabled if all parameters are present and valid
+// New hmac will be enabled if all parameters are present and valid,
+// see Parse for the accepted forms, and for the reason it is not.
 func New(input string) Hmac {
-	params := strings.Split(input, ":")
-	if len(params) != 3 {
+	hmac, err := Parse(input)
+	if err != nil {
 		return Hmac{Enabled: false}
 	}
+	return hmac
+}
 
-	// got 3 params, let's see if they are usable or not
-	alg := params[0]
-	header := params[1]
-	secret := params[2]
+// Parse returns an enabled Hmac from alg:header:secret, or a provider
+// preset such as github:secret. The secret is everything after the last
+// expected separator, so may itself contain colons, and is used as it is.
+func Parse(input string) (Hmac, error) {
+	return parse(input, func(secret string) ([]byte, error) {
+		return []byte(secret), nil
+	})
+}
 
-	hmac := Hmac{Enabled: true}
+// ParseRef parses input as Parse does, but resolves the secret with
+// LoadSecret, so it may be read from a file, a descriptor or, only if
+// allowCmd, a command.
+func ParseRef(input string, allowCmd bool) (Hmac, error) {
+	return parse(input, func(secret string) ([]byte, error) {
+		return LoadSecret(secret, allowCmd)
+	})
+}
 
-	switch alg {
-	case "sha1":
-		hmac.Algorithm = Sha1
-	case "sha512":
-		hmac.Algorithm = Sha512
-	default:
-		hmac.Algorithm = Sha256
+func parse(input string, loadSecret func(string) ([]byte, error)) (Hmac, error) {
+	if input == "" {
+		return Hmac{}, ErrNoDetails
 	}
 
-	if validHeader.MatchString(header) {
-		hmac.Header = header
+	var hmac Hmac
+	var secret string
+	params := strings.SplitN(input, ":", 2)
+	if preset, ok := presets[params[0]]; ok && len(params) == 2 {
+		hmac = preset
+		secret = params[1]
 	} else {
-		hmac.Enabled = false
-		return hmac
+		params = strings.SplitN(input, ":", 3)
+		if len(params) != 3 {
+			return Hmac{}, fmt.Errorf("HMAC details need alg:header:secret, got %d fields", len(params))
+		}
+
+		// got 3 params, let's see if they are usable or not
+		alg := params[0]
+		header := params[1]
+		secret = params[2]
+
+		switch alg {
+		case "sha1":
+			hmac.Algorithm = Sha1
+		case "sha512":
+			hmac.Algorithm = Sha512
+		case "", "sha256":
+			hmac.Algorithm = Sha256
+		default:
+			return Hmac{}, fmt.Errorf("unsupported HMAC algorithm %q", alg)
+		}
+
+		if !validHeader.MatchString(header) {
+			return Hmac{}, fmt.Errorf("invalid HMAC header %q, must be x-name", header)
+		}
+		hmac.Header = header
 	}
 
-	if len(secret) > 0 {
-		hmac.Secret = secret
-	} else {
-		hmac.Enabled = false
+	key, err := loadSecret(secret)
+	if err != nil {
+		return Hmac{}, err
 	}
-	return hmac
+	if len(key) == 0 {
+		return Hmac{}, ErrEmptySecret
+	}
+	hmac.Secret = string(key)
+	hmac.Enabled = true
+	return hmac, nil
 }
 
 // Sign takes an Hmac (with secret, algorithm, and expected header), and
 // a body, and returns an HTTP header, similar to GitHub and GitLab
 // HMACs for authenticating the body, via HTTP header envelope.
-// see https://tools.ietf.org/html/draft-cavage-http-signatures-10 or a
-// later revision for more details on the proposed RFC specification.
+// Only the body is covered, see SignMessage for RFC 9421 HTTP Message
+// Signatures over the method, URL and headers as well.
 func Sign(mac Hmac, body []byte) []byte {
 
 	if !mac.Enabled {
 		return []byte("")
 	}
 
+	// provider schemes may sign a timestamp and so need more headers
+	if mac.Scheme != Plain && mac.Scheme != GitHub {
+		return []byte(Headers(mac, body, time.Now()).Get(mac.Header))
+	}
+	return []byte(envelope(mac, body))
+}
+
+// envelope formats the HMAC of body as alg=hex
+func envelope(mac Hmac, body []byte) string {
+	alg, sum := digest(mac, body)
+	hash := hex.EncodeToString(sum)
+
+	// build required header by appending strings together
+	return strings.Join(
+		[]string{
+			alg,
+			"=",
+			hash},
+		"")
+}
+
+// digest returns the algorithm name and the raw HMAC of body
+func digest(mac Hmac, body []byte) (string, []byte) {
+	alg, macFn := newHMAC(mac)
+	macFn.Write(body)
+	return alg, macFn.Sum(nil)
+}
+
+// newHMAC returns the algorithm name and the HMAC for the mac, to
+// which the signed payload is written
+func newHMAC(mac Hmac) (string, hash.Hash) {
 	var fn func() hash.Hash
 	var alg string
 
@@ -95,17 +180,42 @@ func Sign(mac Hmac, body []byte) []byte {
 		alg = "sha256"
 	}
 
-	macFn := hmac.New(fn, []byte(mac.Secret))
-	macFn.Write(body)
-	hash := hex.EncodeToString(macFn.Sum(nil))
+	return alg, hmac.New(fn, []byte(mac.Secret))
+}
 
-	// build required header by appending strings together
-	httpHeader := strings.Join(
-		[]string{
-			alg,
-			"=",
-			hash},
-		"")
+// Verify reports whether signature is the envelope that Sign would
+// produce for body. The algorithm must match the one configured, and the
+// digests are compared in constant time. Slack signatures also need the
+// timestamp header, and so can only be checked with VerifyHeader.
+func Verify(mac Hmac, body, signature []byte) bool {
+	return verify(mac, body, string(signature), "")
+}
 
-	return []byte(httpHeader)
+// VerifyHeader checks the signature carried in the mac's header, such as
+// on a response or an incoming webhook, returning ErrNoSignature if the
+// header is absent, or ErrMismatch if it does not match body.
+func VerifyHeader(mac Hmac, h http.Header, body []byte) error {
+	signature := h.Get(mac.Header)
+	if signature == "" {
+		return ErrNoSignature
+	}
+	if !verify(mac, body, signature, h.Get(SlackTimestamp)) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// VerifyAny checks the signature against each of macs in turn, so that
+// receivers keep accepting the previous secret during a key rotation.
+func VerifyAny(macs []Hmac, h http.Header, body []byte) error {
+	err := ErrNoSignature
+	for _, mac := range macs {
+		switch VerifyHeader(mac, h, body) {
+		case nil:
+			return nil
+		case ErrMismatch:
+			err = ErrMismatch
+		}
+	}
+	return err
 }
@@ -6,6 +6,7 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"hash"
+	"net/http"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -130,3 +131,89 @@ func TestSignedHeaders(t *testing.T) {
 		}
 	}
 }
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	mac := hamac.Hmac{
+		Enabled:   true,
+		Algorithm: hamac.Sha256,
+		Header:    "x-lol",
+		Secret:    "squirrel"}
+
+	testCases := []struct {
+		name      string
+		mac       hamac.Hmac
+		signature string
+		want      bool
+	}{
+		{
+			name:      "valid signature",
+			mac:       mac,
+			signature: "sha256=82134a1023b182184567609ca9c7dd1c3f0c875fbfff9ad876664f78d5ec2f8d",
+			want:      true,
+		},
+		{
+			name:      "upper case hex and whitespace",
+			mac:       mac,
+			signature: " sha256=82134A1023B182184567609CA9C7DD1C3F0C875FBFFF9AD876664F78D5EC2F8D\n",
+			want:      true,
+		},
+		{
+			name:      "tampered digest",
+			mac:       mac,
+			signature: "sha256=82134a1023b182184567609ca9c7dd1c3f0c875fbfff9ad876664f78d5ec2f8e",
+		},
+		{
+			name:      "algorithm downgrade",
+			mac:       mac,
+			signature: "sha1=82134a1023b182184567609ca9c7dd1c3f0c875fbfff9ad876664f78d5ec2f8d",
+		},
+		{
+			name:      "missing envelope",
+			mac:       mac,
+			signature: "82134a1023b182184567609ca9c7dd1c3f0c875fbfff9ad876664f78d5ec2f8d",
+		},
+		{
+			name:      "not hex",
+			mac:       mac,
+			signature: "sha256=squirrel",
+		},
+		{
+			name:      "disabled",
+			mac:       hamac.Hmac{Enabled: false},
+			signature: "",
+		},
+	}
+
+	body := []byte("content")
+
+	for _, tc := range testCases {
+		got := hamac.Verify(tc.mac, body, []byte(tc.signature))
+		if tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyHeader(t *testing.T) {
+	t.Parallel()
+	mac := hamac.New("sha512:x-cabal:squirrel")
+	body := []byte("content")
+
+	testCases := []struct {
+		name   string
+		header http.Header
+		want   error
+	}{
+		{name: "no header", header: http.Header{}, want: hamac.ErrNoSignature},
+		{name: "mismatch", header: http.Header{"X-Cabal": {"sha512=00"}}, want: hamac.ErrMismatch},
+		{name: "match", header: http.Header{"X-Cabal": {string(hamac.Sign(mac, body))}}, want: nil},
+	}
+
+	for _, tc := range testCases {
+		got := hamac.VerifyHeader(mac, tc.header, body)
+		if tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package hamac
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP Message Signature algorithms, named as in the RFC 9421 registry
+const (
+	AlgEd25519      = "ed25519"
+	AlgEcdsaP256    = "ecdsa-p256-sha256"
+	AlgRsaPss       = "rsa-pss-sha512"
+	AlgRsaPkcs1     = "rsa-v1_5-sha256"
+	AlgHmacSha256   = "hmac-sha256"
+	defaultSigLabel = "sig1"
+)
+
+// DefaultComponents are covered when no component list is given, with
+// content-digest and content-type as well when the request has them
+var DefaultComponents = []string{"@method", "@target-uri"}
+
+// MessageSigner holds a key and the parameters used to produce HTTP
+// Message Signatures, see https://www.rfc-editor.org/rfc/rfc9421
+type MessageSigner struct {
+	Label     string
+	KeyID     string
+	Algorithm string
+	// Components are covered by the signature, or if there are none,
+	// DefaultComponents and those for the content of the request
+	Components []string
+	Created    bool
+	Expires    time.Duration
+	IncludeAlg bool
+	Key        crypto.PrivateKey
+}
+
+// NewMessageSigner loads a private key from a PEM file, or for
+// hmac-sha256 a shared secret from a plain file. An empty algorithm is
+// inferred from the type of key found.
+func NewMessageSigner(alg, keyFile string) (MessageSigner, error) {
+	s := MessageSigner{
+		Label:     defaultSigLabel,
+		Algorithm: normaliseAlg(alg),
+		Created:   true,
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return s, err
+	}
+	if s.Algorithm == AlgHmacSha256 {
+		secret := trimLineEnding(data)
+		if len(secret) == 0 {
+			return s, fmt.Errorf("no secret in %s", keyFile)
+		}
+		s.Key = secret
+		return s, nil
+	}
+
+	s.Key, err = parsePrivateKey(data)
+	if err != nil {
+		return s, fmt.Errorf("%s: %v", keyFile, err)
+	}
+
+	inferred := ""
+	switch k := s.Key.(type) {
+	case ed25519.PrivateKey:
+		inferred = AlgEd25519
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return s, errors.New("only P-256 ECDSA keys are supported")
+		}
+		inferred = AlgEcdsaP256
+	case *rsa.PrivateKey:
+		inferred = AlgRsaPss
+		if s.Algorithm == AlgRsaPkcs1 {
+			inferred = AlgRsaPkcs1
+		}
+	}
+	if s.Algorithm == "" {
+		s.Algorithm = inferred
+	}
+	if s.Algorithm != inferred {
+		return s, fmt.Errorf("key in %s cannot be used with %s", keyFile, s.Algorithm)
+	}
+	return s, nil
+}
+
+// normaliseAlg accepts the short algorithm names as well as RFC ones
+func normaliseAlg(alg string) string {
+	switch strings.ToLower(alg) {
+	case "ecdsa", "ecdsa-p256":
+		return AlgEcdsaP256
+	case "rsa-pss":
+		return AlgRsaPss
+	case "rsa", "rsa-v1_5":
+		return AlgRsaPkcs1
+	case "hmac":
+		return AlgHmacSha256
+	}
+	return strings.ToLower(alg)
+}
+
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// ContentDigest returns an RFC 9530 Content-Digest header value
+func ContentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return contentDigest(sum[:])
+}
+
+func contentDigest(sum []byte) string {
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+}
+
+// SignMessage adds Signature-Input and Signature headers to req, covering
+// the signer's components at time t. A Content-Digest header is added for
+// body if it is covered and not already present. Existing signatures
+// with other labels are kept, while one with the same label is replaced,
+// so a request can be signed again.
+func SignMessage(s MessageSigner, req *http.Request, body []byte, t time.Time) error {
+	if s.Label == "" {
+		s.Label = defaultSigLabel
+	}
+	if len(s.Components) == 0 {
+		s.Components = contentComponents(DefaultComponents, req, body)
+	}
+	for _, c := range s.Components {
+		if strings.ToLower(c) == "content-digest" && req.Header.Get("Content-Digest") == "" {
+			req.Header.Set("Content-Digest", ContentDigest(body))
+		}
+	}
+
+	params := SignatureParams(s, t)
+	base, err := SignatureBase(req, s.Components, params)
+	if err != nil {
+		return err
+	}
+
+	sig, err := signBase(s, []byte(base))
+	if err != nil {
+		return err
+	}
+
+	removeMember(req.Header, "Signature-Input", s.Label)
+	removeMember(req.Header, "Signature", s.Label)
+	appendDictionary(req.Header, "Signature-Input", s.Label+"="+params)
+	appendDictionary(req.Header, "Signature", s.Label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// SignMessageFrom signs req as SignMessage does, with the body read from
+// r, which is streamed to digest it, rather than read into memory.
+func SignMessageFrom(s MessageSigner, req *http.Request, r io.Reader, t time.Time) error {
+	covered := len(s.Components) == 0
+	for _, c := range s.Components {
+		covered = covered || strings.ToLower(c) == "content-digest"
+	}
+	if covered && req.Header.Get("Content-Digest") == "" {
+		sum := sha256.New()
+		n, err := io.Copy(sum, r)
+		if err != nil {
+			return err
+		}
+		// by default, only a body is covered
+		if n > 0 || len(s.Components) > 0 {
+			req.Header.Set("Content-Digest", contentDigest(sum.Sum(nil)))
+		}
+	}
+	return SignMessage(s, req, nil, t)
+}
+
+// contentComponents adds content-digest to components when the request
+// has a body, or that header, and content-type when it has that header
+func contentComponents(components []string, req *http.Request, body []byte) []string {
+	out := append([]string(nil), components...)
+	if len(body) > 0 || req.Header.Get("Content-Digest") != "" {
+		out = append(out, "content-digest")
+	}
+	if req.Header.Get("Content-Type") != "" {
+		out = append(out, "content-type")
+	}
+	return out
+}
+
+func appendDictionary(h http.Header, name, member string) {
+	if existing := h.Get(name); existing != "" {
+		member = existing + ", " + member
+	}
+	h.Set(name, member)
+}
+
+// removeMember removes the member with the key from the dictionary
+// header, keeping the others
+func removeMember(h http.Header, name, key string) {
+	var kept []string
+	for _, v := range h.Values(name) {
+		for _, member := range splitDictionary(v) {
+			k := member
+			if i := strings.IndexByte(member, '='); i >= 0 {
+				k = member[:i]
+			}
+			if k != key {
+				kept = append(kept, member)
+			}
+		}
+	}
+	h.Del(name)
+	if len(kept) > 0 {
+		h.Set(name, strings.Join(kept, ", "))
+	}
+}
+
+// splitDictionary splits a structured field dictionary into its members,
+// at commas outside of quoted strings and inner lists
+func splitDictionary(v string) []string {
+	var members []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			members = append(members, strings.TrimSpace(v[start:i]))
+			start = i + 1
+		}
+	}
+	if member := strings.TrimSpace(v[start:]); member != "" {
+		members = append(members, member)
+	}
+	return members
+}
+
+// SignatureParams serialises the covered components and parameters, as
+// used for both the Signature-Input header and the signature base.
+func SignatureParams(s MessageSigner, t time.Time) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, c := range s.Components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(strings.ToLower(c)))
+	}
+	b.WriteByte(')')
+	if s.Created {
+		b.WriteString(";created=" + strconv.FormatInt(t.Unix(), 10))
+	}
+	if s.Expires > 0 {
+		b.WriteString(";expires=" + strconv.FormatInt(t.Add(s.Expires).Unix(), 10))
+	}
+	if s.KeyID != "" {
+		b.WriteString(";keyid=" + strconv.Quote(s.KeyID))
+	}
+	if s.IncludeAlg {
+		b.WriteString(";alg=" + strconv.Quote(s.Algorithm))
+	}
+	return b.String()
+}
+
+// SignatureBase builds the canonical string that is signed, one line per
+// covered component, followed by the signature parameters.
+func SignatureBase(req *http.Request, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, c := range components {
+		name := strings.ToLower(c)
+		value, err := componentValue(req, name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(strconv.Quote(name) + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return b.String(), nil
+}
+
+func componentValue(req *http.Request, name string) (string, error) {
+	u := req.URL
+	authority := req.Host
+	if authority == "" {
+		authority = u.Host
+	}
+	authority = strings.ToLower(authority)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	switch name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		return strings.ToLower(u.Scheme) + "://" + authority + u.RequestURI(), nil
+	case "@authority":
+		return authority, nil
+	case "@scheme":
+		return strings.ToLower(u.Scheme), nil
+	case "@request-target":
+		return u.RequestURI(), nil
+	case "@path":
+		return path, nil
+	case "@query":
+		return "?" + u.RawQuery, nil
+	case "host":
+		return authority, nil
+	case "content-length":
+		if req.Header.Get("Content-Length") == "" && req.ContentLength >= 0 {
+			return strconv.FormatInt(req.ContentLength, 10), nil
+		}
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported derived component %q", name)
+	}
+
+	values := req.Header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("covered header %q is not present", name)
+	}
+	trimmed := make([]string, len(values))
+	for i := range values {
+		trimmed[i] = strings.TrimSpace(values[i])
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+func signBase(s MessageSigner, base []byte) ([]byte, error) {
+	switch s.Algorithm {
+	case AlgHmacSha256:
+		secret, ok := s.Key.([]byte)
+		if !ok {
+			break
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	case AlgEd25519:
+		key, ok := s.Key.(ed25519.PrivateKey)
+		if !ok {
+			break
+		}
+		return ed25519.Sign(key, base), nil
+	case AlgEcdsaP256:
+		key, ok := s.Key.(*ecdsa.PrivateKey)
+		if !ok {
+			break
+		}
+		digest := sha256.Sum256(base)
+		r, ss, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// RFC 9421 wants the fixed size r || s form, not ASN.1
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		ss.FillBytes(sig[32:])
+		return sig, nil
+	case AlgRsaPss:
+		key, ok := s.Key.(*rsa.PrivateKey)
+		if !ok {
+			break
+		}
+		digest := sha512.Sum512(base)
+		return rsa.SignPSS(rand.Reader, key, crypto.SHA512, digest[:],
+			&rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	case AlgRsaPkcs1:
+		key, ok := s.Key.(*rsa.PrivateKey)
+		if !ok {
+			break
+		}
+		digest := sha256.Sum256(base)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %q", s.Algorithm)
+	}
+	return nil, fmt.Errorf("key cannot be used with %s", s.Algorithm)
+}
@@ -0,0 +1,163 @@
+package hamac
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme selects how a signature is computed and carried: the plain
+// alg=hex envelope, or the scheme of a well known webhook provider.
+type Scheme int
+
+const (
+	Plain Scheme = iota
+	GitHub
+	Stripe
+	Slack
+	Shopify
+)
+
+// SlackTimestamp is the header carrying the time signed by Slack
+const SlackTimestamp = "X-Slack-Request-Timestamp"
+
+// presets reproduce the headers and algorithms each provider uses, and
+// only need a secret to be enabled, as in github:secret
+var presets = map[string]Hmac{
+	"github":  {Scheme: GitHub, Algorithm: Sha256, Header: "X-Hub-Signature-256"},
+	"stripe":  {Scheme: Stripe, Algorithm: Sha256, Header: "Stripe-Signature"},
+	"slack":   {Scheme: Slack, Algorithm: Sha256, Header: "X-Slack-Signature"},
+	"shopify": {Scheme: Shopify, Algorithm: Sha256, Header: "X-Shopify-Hmac-Sha256"},
+}
+
+// Headers returns every header needed to carry the signature of body,
+// using t for the schemes that sign a timestamp along with the body.
+func Headers(mac Hmac, body []byte, t time.Time) http.Header {
+	h, _ := HeadersFrom(mac, bytes.NewReader(body), t)
+	return h
+}
+
+// HeadersFrom is Headers for a body read from r, which is read once, so
+// a body too large to hold in memory can be signed as it is streamed.
+func HeadersFrom(mac Hmac, r io.Reader, t time.Time) (http.Header, error) {
+	h := make(http.Header)
+	if !mac.Enabled {
+		return h, nil
+	}
+
+	ts := strconv.FormatInt(t.Unix(), 10)
+	alg, sum := newHMAC(mac)
+	w := io.Writer(sum)
+	// GitHub still sends the legacy sha1 header alongside
+	legacy := mac
+	legacy.Algorithm = Sha1
+	_, legacySum := newHMAC(legacy)
+	switch mac.Scheme {
+	case GitHub:
+		w = io.MultiWriter(sum, legacySum)
+	case Stripe:
+		io.WriteString(sum, ts+".")
+	case Slack:
+		io.WriteString(sum, "v0:"+ts+":")
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+
+	switch mac.Scheme {
+	case GitHub:
+		h.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(legacySum.Sum(nil)))
+		h.Set(mac.Header, alg+"="+hex.EncodeToString(sum.Sum(nil)))
+	case Stripe:
+		h.Set(mac.Header, "t="+ts+",v1="+hex.EncodeToString(sum.Sum(nil)))
+	case Slack:
+		h.Set(mac.Header, "v0="+hex.EncodeToString(sum.Sum(nil)))
+		h.Set(SlackTimestamp, ts)
+	case Shopify:
+		h.Set(mac.Header, base64.StdEncoding.EncodeToString(sum.Sum(nil)))
+	default:
+		h.Set(mac.Header, alg+"="+hex.EncodeToString(sum.Sum(nil)))
+	}
+	return h, nil
+}
+
+func stripePayload(ts string, body []byte) []byte {
+	return append([]byte(ts+"."), body...)
+}
+
+func slackPayload(ts string, body []byte) []byte {
+	return append([]byte("v0:"+ts+":"), body...)
+}
+
+// verify checks signature against body according to the mac's scheme.
+// The timestamp is only needed for Slack, which sends it separately.
+func verify(mac Hmac, body []byte, signature, timestamp string) bool {
+	if !mac.Enabled {
+		return false
+	}
+	signature = strings.TrimSpace(signature)
+
+	switch mac.Scheme {
+	case Stripe:
+		var ts string
+		var candidates [][]byte
+		for _, item := range strings.Split(signature, ",") {
+			kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "t":
+				ts = kv[1]
+			case "v1":
+				if sum, err := hex.DecodeString(kv[1]); err == nil {
+					candidates = append(candidates, sum)
+				}
+			}
+		}
+		if ts == "" {
+			return false
+		}
+		_, want := digest(mac, stripePayload(ts, body))
+		for _, got := range candidates {
+			if hmac.Equal(want, got) {
+				return true
+			}
+		}
+		return false
+	case Slack:
+		if timestamp == "" || !strings.HasPrefix(signature, "v0=") {
+			return false
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+		if err != nil {
+			return false
+		}
+		_, want := digest(mac, slackPayload(timestamp, body))
+		return hmac.Equal(want, got)
+	case Shopify:
+		got, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return false
+		}
+		_, want := digest(mac, body)
+		return hmac.Equal(want, got)
+	}
+
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	got, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	alg, want := digest(mac, body)
+	return parts[0] == alg && hmac.Equal(want, got)
+}
@@ -0,0 +1,119 @@
+package hamac_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skunkwerks/gurl/hamac"
+)
+
+// generate these signatures via:
+// printf '1618884473.content' | openssl dgst -sha256 -hmac squirrel
+// printf 'content' | openssl dgst -sha256 -hmac squirrel -binary | base64
+func TestPresets(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		input string
+		want  http.Header
+	}{
+		{
+			name:  "github",
+			input: "github:squirrel",
+			want: http.Header{
+				"X-Hub-Signature":     {"sha1=7363e0cba660e7bf3ac58103883cc08a745188a3"},
+				"X-Hub-Signature-256": {"sha256=82134a1023b182184567609ca9c7dd1c3f0c875fbfff9ad876664f78d5ec2f8d"},
+			},
+		},
+		{
+			name:  "stripe",
+			input: "stripe:squirrel",
+			want: http.Header{
+				"Stripe-Signature": {"t=1618884473,v1=c7f3a63a44e8a5fe2dc62d59a7051df512692527f06f8cbbbd2dfb42d5d95a7f"},
+			},
+		},
+		{
+			name:  "slack",
+			input: "slack:squirrel",
+			want: http.Header{
+				"X-Slack-Signature":         {"v0=8dee25d92bc6b40a0ca9437aed9d3edcf925bd902fca0e8a7be60acb1255f202"},
+				"X-Slack-Request-Timestamp": {"1618884473"},
+			},
+		},
+		{
+			name:  "shopify",
+			input: "shopify:squirrel",
+			want: http.Header{
+				"X-Shopify-Hmac-Sha256": {"ghNKECOxghhFZ2CcqcfdHD8Mh1+//5rYdmZPeNXsL40="},
+			},
+		},
+		{
+			name:  "missing secret",
+			input: "github:",
+			want:  http.Header{},
+		},
+		{
+			name:  "unknown preset",
+			input: "gitlab:squirrel",
+			want:  http.Header{},
+		},
+	}
+
+	body := []byte("content")
+	now := time.Unix(1618884473, 0)
+
+	for _, tc := range testCases {
+		mac := hamac.New(tc.input)
+		got := hamac.Headers(mac, body, now)
+		if !cmp.Equal(tc.want, got) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got))
+		}
+		if !mac.Enabled {
+			continue
+		}
+
+		if err := hamac.VerifyHeader(mac, got, body); err != nil {
+			t.Errorf("%v: own signature failed to verify: %v", tc.name, err)
+		}
+		if err := hamac.VerifyHeader(mac, got, []byte("tampered")); err != hamac.ErrMismatch {
+			t.Errorf("%v: tampered body wanted %v, got %v", tc.name, hamac.ErrMismatch, err)
+		}
+	}
+}
+
+func TestVerifyStripeMultipleSignatures(t *testing.T) {
+	t.Parallel()
+	mac := hamac.New("stripe:squirrel")
+	// Stripe sends one v1 signature per active secret during rotation
+	signature := "t=1618884473,v1=0000,v1=c7f3a63a44e8a5fe2dc62d59a7051df512692527f06f8cbbbd2dfb42d5d95a7f,v0=ignored"
+	if !hamac.Verify(mac, []byte("content"), []byte(signature)) {
+		t.Errorf("wanted any matching v1 signature to verify")
+	}
+}
+
+func TestHeadersFrom(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1618884473, 0)
+	for _, input := range []string{"github:squirrel", "stripe:squirrel", "slack:squirrel", "shopify:squirrel"} {
+		mac := hamac.New(input)
+		got, err := hamac.HeadersFrom(mac, strings.NewReader("content"), now)
+		if err != nil {
+			t.Fatalf("%v: %v", input, err)
+		}
+		want := hamac.Headers(mac, []byte("content"), now)
+		if !cmp.Equal(want, got) {
+			t.Errorf("%v: diff %v", input, cmp.Diff(want, got))
+		}
+	}
+
+	failed := errors.New("failed")
+	mac := hamac.New("github:squirrel")
+	if _, err := hamac.HeadersFrom(mac, iotest.ErrReader(failed), now); !errors.Is(err, failed) {
+		t.Errorf("wanted the read error, got %v", err)
+	}
+}
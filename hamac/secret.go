@@ -0,0 +1,109 @@
+package hamac
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrSecretCmd is returned for a cmd: secret when commands are not allowed
+var ErrSecretCmd = errors.New("cmd: secrets run a command, which is not allowed")
+
+// LoadSecret resolves a secret reference, so that secrets need not sit
+// in the environment in the clear:
+//
+//	file:/run/secrets/hmac   contents of a file
+//	fd:3                     contents of an inherited file descriptor
+//	cmd:pass show api/hmac   output of a credential command
+//	env:HMAC_SECRET          value of another environment variable
+//	base64:c3F1aXJyZWw=      decoded, and may wrap any of the above
+//	hex:7371756972726573     decoded, and may wrap any of the above
+//	raw:file:literal         the literal remainder, for awkward secrets
+//
+// Anything else is used literally. A single trailing line ending is
+// dropped from files, descriptors and command output. A command is only
+// run if allowCmd, otherwise it is ErrSecretCmd.
+func LoadSecret(ref string, allowCmd bool) ([]byte, error) {
+	kind := strings.SplitN(ref, ":", 2)
+	if len(kind) != 2 {
+		return []byte(ref), nil
+	}
+	value := kind[1]
+
+	switch kind[0] {
+	case "raw":
+		return []byte(value), nil
+	case "env":
+		secret, ok := os.LookupEnv(value)
+		if !ok {
+			return nil, fmt.Errorf("secret env var %s is not set", value)
+		}
+		return []byte(secret), nil
+	case "file":
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		return trimLineEnding(data), nil
+	case "fd":
+		fd, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret file descriptor %q", value)
+		}
+		f := os.NewFile(uintptr(fd), "fd"+value)
+		if f == nil {
+			return nil, fmt.Errorf("invalid secret file descriptor %q", value)
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return trimLineEnding(data), nil
+	case "cmd":
+		if !allowCmd {
+			return nil, ErrSecretCmd
+		}
+		shell, flag := "sh", "-c"
+		if runtime.GOOS == "windows" {
+			shell, flag = "cmd", "/C"
+		}
+		cmd := exec.Command(shell, flag, value)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("secret command failed: %v", err)
+		}
+		return trimLineEnding(out), nil
+	case "base64":
+		encoded, err := LoadSecret(value, allowCmd)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	case "hex":
+		encoded, err := LoadSecret(value, allowCmd)
+		if err != nil {
+			return nil, err
+		}
+		return hex.DecodeString(strings.TrimSpace(string(encoded)))
+	}
+	return []byte(ref), nil
+}
+
+// trimLineEnding drops one trailing newline, or CRLF, as secrets may be
+// binary and must otherwise be kept intact.
+func trimLineEnding(data []byte) []byte {
+	s := string(data)
+	if strings.HasSuffix(s, "\n") {
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+	}
+	return []byte(s)
+}
@@ -0,0 +1,187 @@
+package hamac_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skunkwerks/gurl/hamac"
+)
+
+func TestLoadSecret(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain")
+	encoded := filepath.Join(dir, "encoded")
+	if err := os.WriteFile(plain, []byte("squirrel\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(encoded, []byte("c3F1aXJyZWw=\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", ref: "squirrel", want: "squirrel"},
+		{name: "literal with colons", ref: "sq:ui:rrel", want: "sq:ui:rrel"},
+		{name: "raw escape", ref: "raw:file:squirrel", want: "file:squirrel"},
+		{name: "file", ref: "file:" + plain, want: "squirrel"},
+		{name: "missing file", ref: "file:" + filepath.Join(dir, "nope"), wantErr: true},
+		{name: "command", ref: "cmd:echo squirrel", want: "squirrel"},
+		{name: "failing command", ref: "cmd:exit 1", wantErr: true},
+		{name: "base64", ref: "base64:c3F1aXJyZWw=", want: "squirrel"},
+		{name: "hex", ref: "hex:737175697272656c", want: "squirrel"},
+		{name: "bad hex", ref: "hex:squirrel", wantErr: true},
+		{name: "base64 file", ref: "base64:file:" + encoded, want: "squirrel"},
+		{name: "bad fd", ref: "fd:x", wantErr: true},
+		{name: "unset env", ref: "env:GURL_TEST_UNSET_SECRET", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := hamac.LoadSecret(tc.ref, true)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%v: wanted an error, got %q", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !cmp.Equal(tc.want, string(got)) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, string(got)))
+		}
+	}
+}
+
+func TestLoadSecretCmd(t *testing.T) {
+	t.Parallel()
+	for _, ref := range []string{"cmd:echo squirrel", "base64:cmd:echo c3F1aXJyZWw="} {
+		if _, err := hamac.LoadSecret(ref, false); !errors.Is(err, hamac.ErrSecretCmd) {
+			t.Errorf("%v: ran a command that was not allowed, %v", ref, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		input   string
+		want    hamac.Hmac
+		wantErr bool
+	}{
+		{name: "empty", input: "", wantErr: true},
+		{name: "unknown algorithm", input: "md5:x-sig:squirrel", wantErr: true},
+		{name: "invalid header", input: "sha256:sig:squirrel", wantErr: true},
+		{name: "empty secret", input: "sha256:x-sig:", wantErr: true},
+		{name: "secret with colons", input: "sha256:x-sig:a:b:c",
+			want: hamac.Hmac{
+				Enabled:   true,
+				Algorithm: hamac.Sha256,
+				Header:    "x-sig",
+				Secret:    "a:b:c"}},
+		{name: "secret references are literal", input: "sha256:x-sig:env:HOME",
+			want: hamac.Hmac{
+				Enabled:   true,
+				Algorithm: hamac.Sha256,
+				Header:    "x-sig",
+				Secret:    "env:HOME"}},
+	}
+
+	for _, tc := range testCases {
+		got, err := hamac.Parse(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%v: wanted an error, got %v", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		input    string
+		allowCmd bool
+		want     hamac.Hmac
+		wantErr  bool
+	}{
+		{name: "empty encoded secret", input: "sha256:x-sig:base64:", wantErr: true},
+		{name: "command not allowed", input: "sha256:x-sig:cmd:echo squirrel", wantErr: true},
+		{name: "command", input: "sha256:x-sig:cmd:echo squirrel", allowCmd: true,
+			want: hamac.Hmac{
+				Enabled:   true,
+				Algorithm: hamac.Sha256,
+				Header:    "x-sig",
+				Secret:    "squirrel"}},
+		{name: "preset with encoded secret", input: "shopify:hex:00ff",
+			want: hamac.Hmac{
+				Enabled:   true,
+				Scheme:    hamac.Shopify,
+				Algorithm: hamac.Sha256,
+				Header:    "X-Shopify-Hmac-Sha256",
+				Secret:    "\x00\xff"}},
+	}
+
+	for _, tc := range testCases {
+		got, err := hamac.ParseRef(tc.input, tc.allowCmd)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%v: wanted an error, got %v", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyAny(t *testing.T) {
+	t.Parallel()
+	current := hamac.New("sha256:x-sig:new_secret")
+	previous := hamac.New("sha256:x-sig:old_secret")
+	other := hamac.New("sha256:x-sig:other_secret")
+	body := []byte("content")
+
+	testCases := []struct {
+		name   string
+		signer hamac.Hmac
+		keys   []hamac.Hmac
+		want   error
+	}{
+		{name: "current key", signer: current, keys: []hamac.Hmac{current, previous}, want: nil},
+		{name: "previous key", signer: previous, keys: []hamac.Hmac{current, previous}, want: nil},
+		{name: "retired key", signer: other, keys: []hamac.Hmac{current, previous}, want: hamac.ErrMismatch},
+		{name: "no keys", signer: current, keys: nil, want: hamac.ErrNoSignature},
+	}
+
+	for _, tc := range testCases {
+		h := http.Header{"X-Sig": {string(hamac.Sign(tc.signer, body))}}
+		if got := hamac.VerifyAny(tc.keys, h, body); tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
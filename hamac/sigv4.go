@@ -0,0 +1,319 @@
+package hamac
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4Terminator = "aws4_request"
+	sigV4DateTime   = "20060102T150405Z"
+	sigV4Date       = "20060102"
+
+	// UnsignedPayload is sent in place of the body hash when the body
+	// is deliberately excluded from the signature, as S3 permits.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// SigV4 holds the credentials and scope needed to sign a request with
+// AWS Signature Version 4, as used by S3-compatible stores like MinIO,
+// and by API Gateway endpoints.
+type SigV4 struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+	Unsigned     bool
+}
+
+// NewSigV4 builds SigV4 credentials for a scope of REGION:SERVICE, or
+// just SERVICE, in which case the region is taken from AWS_REGION,
+// AWS_DEFAULT_REGION or the profile. Keys come from the named profile in
+// the shared credentials file if one is given, otherwise from the usual
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables,
+// falling back to AWS_PROFILE or the default profile.
+func NewSigV4(scope, profile string) (SigV4, error) {
+	var v SigV4
+
+	params := strings.Split(scope, ":")
+	switch len(params) {
+	case 1:
+		v.Service = params[0]
+	case 2:
+		v.Region = params[0]
+		v.Service = params[1]
+	default:
+		return v, fmt.Errorf("invalid SigV4 scope %q, want REGION:SERVICE", scope)
+	}
+	if v.Service == "" {
+		return v, errors.New("SigV4 scope is missing a service")
+	}
+
+	if profile == "" && os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		v.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		v.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		v.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	} else {
+		if profile == "" {
+			profile = os.Getenv("AWS_PROFILE")
+		}
+		if profile == "" {
+			profile = "default"
+		}
+		creds, err := readIni(awsFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"))
+		if err != nil {
+			return v, err
+		}
+		section, ok := creds[profile]
+		if !ok {
+			return v, fmt.Errorf("AWS profile %q not found", profile)
+		}
+		v.AccessKey = section["aws_access_key_id"]
+		v.SecretKey = section["aws_secret_access_key"]
+		v.SessionToken = section["aws_session_token"]
+	}
+	if v.AccessKey == "" || v.SecretKey == "" {
+		return v, errors.New("no AWS access key and secret key found")
+	}
+
+	if v.Region == "" {
+		v.Region = os.Getenv("AWS_REGION")
+	}
+	if v.Region == "" {
+		v.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if v.Region == "" {
+		// config is optional, a missing file just means no region
+		config, _ := readIni(awsFile("AWS_CONFIG_FILE", "config"))
+		name := "profile " + profile
+		if profile == "" || profile == "default" {
+			name = "default"
+		}
+		v.Region = config[name]["region"]
+	}
+	if v.Region == "" {
+		return v, errors.New("no AWS region given or configured")
+	}
+	return v, nil
+}
+
+// awsFile returns the path in env if set, or the named file in ~/.aws
+func awsFile(env, name string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", name)
+}
+
+// readIni parses the minimal INI dialect used by the AWS shared files
+func readIni(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	var current map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			current = make(map[string]string)
+			sections[name] = current
+		case current != nil:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) == 2 {
+				current[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// PayloadHash returns the hex encoded SHA256 of body, as SigV4 expects
+func PayloadHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// PayloadHashFrom returns the PayloadHash of the body read from r, which
+// is streamed rather than read into memory
+func PayloadHashFrom(r io.Reader) (string, error) {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// SigningKey derives the SigV4 signing key for a given day and scope
+func SigningKey(secret, date, region, service string) []byte {
+	key := hmacSha256([]byte("AWS4"+secret), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	return hmacSha256(key, sigV4Terminator)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// SignV4 adds the X-Amz-* and Authorization headers to req, signing it
+// at time t. The payloadHash is the hex SHA256 of the body, or
+// UnsignedPayload. The request URL, Host, and any signed headers must
+// not be altered afterwards.
+func SignV4(v SigV4, req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format(sigV4DateTime)
+	date := t.Format(sigV4Date)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if v.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", v.SessionToken)
+	}
+	if v.Service == "s3" || payloadHash == UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers, signedHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join(
+		[]string{
+			req.Method,
+			canonicalURI(req.URL, v.Service),
+			canonicalQuery(req.URL),
+			headers,
+			signedHeaders,
+			payloadHash},
+		"\n")
+
+	scope := strings.Join([]string{date, v.Region, v.Service, sigV4Terminator}, "/")
+	stringToSign := strings.Join(
+		[]string{
+			sigV4Algorithm,
+			amzDate,
+			scope,
+			PayloadHash([]byte(canonicalRequest))},
+		"\n")
+
+	key := SigningKey(v.SecretKey, date, v.Region, v.Service)
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, v.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalHeaders returns the canonical header block, and the list of
+// signed header names. Only the host, content type, and x-amz-* headers
+// are signed, as proxies and the client are free to alter the others.
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || name == "content-md5" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(v))
+			for i := range v {
+				trimmed[i] = strings.Join(strings.Fields(v[i]), " ")
+			}
+			values[name] = strings.Join(trimmed, ",")
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(values[name])
+		b.WriteByte('\n')
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalURI encodes the path once for S3, and twice for all other
+// services, as their respective specifications require.
+func canonicalURI(u *url.URL, service string) string {
+	path := u.Path
+	if service != "s3" {
+		path = u.EscapedPath()
+	}
+	if path == "" {
+		return "/"
+	}
+	return uriEncode(path, false)
+}
+
+func canonicalQuery(u *url.URL) string {
+	query, _ := url.ParseQuery(u.RawQuery)
+	keys := make([]string, 0, len(query))
+	encoded := make(map[string][]string, len(query))
+	for k, vs := range query {
+		key := uriEncode(k, true)
+		keys = append(keys, key)
+		for _, v := range vs {
+			encoded[key] = append(encoded[key], uriEncode(v, true))
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		sort.Strings(encoded[k])
+		for _, v := range encoded[k] {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes everything but RFC 3986 unreserved characters,
+// and optionally the path separator.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexUpper = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexUpper[c>>4])
+			b.WriteByte(hexUpper[c&15])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,193 @@
+package hamac_test
+
+import (
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skunkwerks/gurl/hamac"
+)
+
+// test vectors from the AWS General Reference "Signature Version 4
+// signing process" examples, using their published example keys.
+const (
+	exampleAccessKey = "AKIDEXAMPLE"
+	exampleSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+func TestSigningKey(t *testing.T) {
+	t.Parallel()
+	want := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+	got := hex.EncodeToString(hamac.SigningKey(exampleSecretKey, "20150830", "us-east-1", "iam"))
+	if !cmp.Equal(want, got) {
+		t.Errorf("signing key: diff %v", cmp.Diff(want, got))
+	}
+}
+
+func TestSignV4(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+		creds   hamac.SigV4
+		payload string
+		want    map[string]string
+	}{
+		{
+			name:    "iam example",
+			method:  "GET",
+			url:     "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			creds: hamac.SigV4{
+				AccessKey: exampleAccessKey,
+				SecretKey: exampleSecretKey,
+				Region:    "us-east-1",
+				Service:   "iam"},
+			payload: hamac.PayloadHash(nil),
+			want: map[string]string{
+				"X-Amz-Date": "20150830T123600Z",
+				"Authorization": "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+					"SignedHeaders=content-type;host;x-amz-date, " +
+					"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+			},
+		},
+		{
+			name:   "s3 unsigned payload with session token",
+			method: "PUT",
+			url:    "http://localhost:9000/bucket/key",
+			creds: hamac.SigV4{
+				AccessKey:    exampleAccessKey,
+				SecretKey:    exampleSecretKey,
+				SessionToken: "token",
+				Region:       "us-east-1",
+				Service:      "s3"},
+			payload: hamac.UnsignedPayload,
+			want: map[string]string{
+				"X-Amz-Content-Sha256": "UNSIGNED-PAYLOAD",
+				"X-Amz-Security-Token": "token",
+			},
+		},
+	}
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		hamac.SignV4(tc.creds, req, tc.payload, now)
+		for k, want := range tc.want {
+			if got := req.Header.Get(k); !cmp.Equal(want, got) {
+				t.Errorf("%v: %v diff %v", tc.name, k, cmp.Diff(want, got))
+			}
+		}
+	}
+}
+
+func TestPayloadHashFrom(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		"":        "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"a=b&c=d": "703820ccaccb60bb7a1563d6e6736bcc261c8c061f8d8896103cccf6bfe41e33",
+	}
+	for body, want := range testCases {
+		got, err := hamac.PayloadHashFrom(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want || got != hamac.PayloadHash([]byte(body)) {
+			t.Errorf("%q: hash %v, wanted %v", body, got, want)
+		}
+	}
+}
+
+func TestNewSigV4(t *testing.T) {
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials")
+	config := filepath.Join(dir, "config")
+	err := os.WriteFile(credentials, []byte("[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = secret\n\n[minio]\naws_access_key_id=minioadmin\naws_secret_access_key=minioadmin\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(config, []byte("[profile minio]\nregion = eu-west-1\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	t.Setenv("AWS_CONFIG_FILE", config)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	testCases := []struct {
+		name    string
+		scope   string
+		profile string
+		env     map[string]string
+		want    hamac.SigV4
+		wantErr bool
+	}{
+		{name: "empty scope", scope: "", wantErr: true},
+		{name: "too many fields", scope: "a:b:c", wantErr: true},
+		{name: "no region", scope: "s3", wantErr: true},
+		{name: "missing profile", scope: "us-east-1:s3", profile: "nope", wantErr: true},
+		{name: "profile with configured region", scope: "s3", profile: "minio",
+			want: hamac.SigV4{
+				AccessKey: "minioadmin",
+				SecretKey: "minioadmin",
+				Region:    "eu-west-1",
+				Service:   "s3"}},
+		{name: "default profile", scope: "us-east-1:execute-api",
+			want: hamac.SigV4{
+				AccessKey: "AKIDDEFAULT",
+				SecretKey: "secret",
+				Region:    "us-east-1",
+				Service:   "execute-api"}},
+		{name: "environment", scope: "s3",
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "AKIDENV",
+				"AWS_SECRET_ACCESS_KEY": "envsecret",
+				"AWS_SESSION_TOKEN":     "envtoken",
+				"AWS_REGION":            "ap-south-1"},
+			want: hamac.SigV4{
+				AccessKey:    "AKIDENV",
+				SecretKey:    "envsecret",
+				SessionToken: "envtoken",
+				Region:       "ap-south-1",
+				Service:      "s3"}},
+	}
+
+	for _, tc := range testCases {
+		for k, v := range tc.env {
+			os.Setenv(k, v)
+		}
+		got, err := hamac.NewSigV4(tc.scope, tc.profile)
+		for k := range tc.env {
+			os.Setenv(k, "")
+		}
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%v: wanted an error, got %v", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if tc.want != got {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/skunkwerks/gurl/hamac"
 	"github.com/skunkwerks/gurl/httplib"
 )
 
@@ -25,10 +26,20 @@ var defaultSetting = httplib.BeegoHttpSettings{
 }
 
 func getHTTP(method string, url string, args []string) (r *httplib.BeegoHttpRequest) {
+	items := parseItems(args)
+	// files make the body multipart, with the other items as its parts
+	multipart := multipartType != ""
+	for _, it := range items {
+		multipart = multipart || it.sep == "@"
+	}
 	r = httplib.NewBeegoRequest(url, method)
+	r.Multipart(multipartType)
 	r.Setting(defaultSetting)
-	r.Header("Accept-Encoding", "gzip, deflate")
-	if *isjson {
+	r.Header("Accept-Encoding", httplib.AcceptEncoding)
+	if *isjson && bodyFormat != "json" {
+		r.Header("Accept", bodyFormats[bodyFormat]+", application/json;q=0.9")
+		r.Header("Content-Type", bodyFormats[bodyFormat])
+	} else if *isjson {
 		r.Header("Accept", "application/json")
 		r.Header("Content-Type", "application/json")
 	} else if form || method == "GET" {
@@ -36,72 +47,80 @@ func getHTTP(method string, url string, args []string) (r *httplib.BeegoHttpRequ
 	} else {
 		r.Header("Accept", "application/json")
 	}
-	for i := range args {
-		// Json raws
-		strs := strings.SplitN(args[i], ":=", 2)
-		if len(strs) == 2 {
-			if strings.HasPrefix(strs[1], "@") {
-				f, err := os.Open(strings.TrimLeft(strs[1], "@"))
-				if err != nil {
-					log.Fatal("Read File", strings.TrimLeft(strs[1], "@"), err)
-				}
-				content, err := io.ReadAll(f)
-				if err != nil {
-					log.Fatal("ReadAll from File", strings.TrimLeft(strs[1], "@"), err)
-				}
-				var j interface{}
-				err = json.Unmarshal(content, &j)
-				if err != nil {
-					log.Fatal("Read from File", strings.TrimLeft(strs[1], "@"), "Unmarshal", err)
-				}
-				jsonmap[strs[0]] = j
+	for k, v := range configHeaders {
+		v, err := expandVars(v)
+		if err != nil {
+			log.Fatalf("config header %s: %v", k, err)
+		}
+		r.Header(k, v)
+	}
+	// the first of each header replaces any default, the rest are added
+	headers := make(map[string]bool)
+	for _, it := range items {
+		switch it.sep {
+		case "==":
+			r.Query(it.key, it.value)
+		case ":", ";", ":@":
+			value := it.value
+			if it.sep == ":@" {
+				value = strings.TrimRight(string(readItemFile(value)), "\r\n")
+			} else if it.sep == ":" && value == "" {
+				// Header: removes it, even if it is one gurl sets
+				r.RemoveHeader(it.key)
+				delete(headers, http.CanonicalHeaderKey(it.key))
 				continue
 			}
-			jsonmap[strs[0]] = json.RawMessage(strs[1])
-			continue
-		}
-		// Headers
-		strs = strings.Split(args[i], ":")
-		if len(strs) >= 2 {
-			if strs[0] == "Host" {
-				r.SetHost(strings.Join(strs[1:], ":"))
+			key := http.CanonicalHeaderKey(it.key)
+			if key == "Host" {
+				r.SetHost(value)
 			}
-			r.Header(strs[0], strings.Join(strs[1:], ":"))
-			continue
-		}
-		// files
-		strs = strings.SplitN(args[i], "@", 2)
-		if !*isjson && len(strs) == 2 {
-			if !form {
-				log.Fatal("file upload only support in forms style: -f=true")
+			if headers[key] {
+				r.AddHeader(key, value)
+			} else {
+				r.Header(key, value)
+				headers[key] = true
 			}
-			r.PostFile(strs[0], strs[1])
-			continue
-		}
-		// Params
-		strs = strings.SplitN(args[i], "=", 2)
-		if len(strs) == 2 {
-			if strings.HasPrefix(strs[1], "@") {
-				f, err := os.Open(strings.TrimLeft(strs[1], "@"))
-				if err != nil {
-					log.Fatal("Read File", strings.TrimLeft(strs[1], "@"), err)
-				}
-				content, err := io.ReadAll(f)
-				if err != nil {
-					log.Fatal("ReadAll from File", strings.TrimLeft(strs[1], "@"), err)
-				}
-				strs[1] = string(content)
+		case ":=", ":=@":
+			raw := []byte(it.value)
+			if it.sep == ":=@" {
+				raw = readItemFile(it.value)
+			}
+			var j interface{}
+			if err := json.Unmarshal(raw, &j); err != nil {
+				log.Fatal("request item ", it.key, ": invalid JSON: ", err)
 			}
-			if form || method == "GET" {
-				r.Param(strs[0], strs[1])
+			if multipart {
+				r.PostPart(httplib.Part{Name: it.key, ContentType: "application/json", Body: bytes.NewReader(raw)})
+				continue
+			}
+			if it.sep == ":=" {
+				// keep numbers as they were written
+				j = json.RawMessage(raw)
+			}
+			setJSONItem(it.key, j)
+		case "@":
+			r.PostPart(filePart(it.key, it.value))
+			uploading = true
+		case "=", "=@":
+			value := it.value
+			if it.sep == "=@" {
+				value = string(readItemFile(value))
+			}
+			if form || multipart || method == "GET" {
+				r.Param(it.key, value)
 			} else {
-				jsonmap[strs[0]] = strs[1]
+				setJSONItem(it.key, value)
 			}
-			continue
 		}
 	}
-	if !form && len(jsonmap) > 0 {
-		_, err := r.JsonBody(jsonmap)
+	if !form && jsonItems != nil && bodyFormat != "json" {
+		data, err := encodeItems(jsonItems, bodyFormat)
+		if err != nil {
+			log.Fatalf("fail to encode %s: %v", bodyFormat, err)
+		}
+		r.Body(data)
+	} else if !form && jsonItems != nil {
+		_, err := r.JsonBody(jsonItems)
 		if err != nil {
 			log.Fatal("fail to marshal JSON: ", err)
 		}
@@ -109,25 +128,76 @@ func getHTTP(method string, url string, args []string) (r *httplib.BeegoHttpRequ
 	return
 }
 
+// setJSONItem sets a JSON request item, whose key may be a nested path
+func setJSONItem(key string, value interface{}) {
+	var err error
+	jsonItems, err = setItem(jsonItems, key, value)
+	if err != nil {
+		log.Fatal("request item ", err)
+	}
+}
+
+// verifyResponse checks the HMAC of the response body as it was sent,
+// before it is decoded, and then leaves the body to be read again
+func verifyResponse(res *http.Response, macs []hamac.Hmac) error {
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return hamac.VerifyAny(macs, res.Header, body)
+}
+
 func formatResponseBody(res *http.Response, httpreq *httplib.BeegoHttpRequest, pretty bool) string {
 	body, err := httpreq.Bytes()
 	if err != nil {
 		log.Fatalln("can't get the url", err)
 	}
 	fmt.Println("")
-	match, err := regexp.MatchString(contentJsonRegex, res.Header.Get("Content-Type"))
+	// an encoded body is binary, whatever its Content-Type
+	if rawEncoding && res.Header.Get("Content-Encoding") != "" {
+		return string(body)
+	}
+	contentType := res.Header.Get("Content-Type")
+	str, err := formatBody(body, contentType, pretty)
 	if err != nil {
-		log.Fatalln("failed to compile regex", err)
+		if ok, _ := regexp.MatchString(contentJsonRegex, contentType); ok {
+			log.Fatal("Response Indent: ", err)
+		}
+		// other formats, such as YAML and binary ones, are shown as sent
+		note := fmt.Sprintf("can't format the %s body, shown as it is: %v", contentType, err)
+		if interactive {
+			fmt.Println(Color(note, Gray))
+		} else {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		return string(body)
 	}
-	if pretty && match {
-		var output bytes.Buffer
-		err := json.Indent(&output, body, "", "  ")
+	return str
+}
+
+// formatBody indents bodies for pretty printing, by their content type,
+// returning the body unchanged if there is no formatter for it, along
+// with any error if it is invalid.
+func formatBody(body []byte, contentType string, pretty bool) (string, error) {
+	f, ok := formatterFor(contentType)
+	if !ok {
+		return string(body), nil
+	}
+	if f.decode != nil {
+		decoded, err := f.decode(body, contentType)
 		if err != nil {
-			log.Fatal("Response JSON Indent: ", err)
+			return string(body), err
 		}
-
-		return output.String()
+		body = decoded
 	}
-
-	return string(body)
+	if !pretty {
+		return string(body), nil
+	}
+	str, err := f.indent(body)
+	if err != nil {
+		return string(body), err
+	}
+	return str, nil
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/skunkwerks/gurl/hamac"
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+func TestVerifyResponse(t *testing.T) {
+	mac := hamac.New("sha256:x-sig:squirrel")
+	data := []byte(`{"status":"ok"}`)
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(data)
+	zw.Close()
+
+	testCases := []struct {
+		name   string
+		signed []byte
+		want   error
+	}{
+		{name: "signed as sent", signed: gzipped.Bytes()},
+		{name: "signed decoded", signed: data, want: hamac.ErrMismatch},
+		{name: "unsigned", want: hamac.ErrNoSignature},
+	}
+
+	for _, tc := range testCases {
+		res := &http.Response{
+			Header: http.Header{"Content-Encoding": {"gzip"}},
+			Body:   io.NopCloser(bytes.NewReader(gzipped.Bytes())),
+		}
+		if tc.signed != nil {
+			res.Header.Set("X-Sig", string(hamac.Sign(mac, tc.signed)))
+		}
+		if err := verifyResponse(res, []hamac.Hmac{mac}); err != tc.want {
+			t.Errorf("%v: wanted %v, got %v", tc.name, tc.want, err)
+		}
+		// the body is still there to decode and show
+		decoded, err := httplib.DecodedBody(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, err := io.ReadAll(decoded); err != nil || !bytes.Equal(body, data) {
+			t.Errorf("%v: unexpected body %q %v", tc.name, body, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package httplib
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// AcceptEncoding lists every content coding that DecodedBody undoes
+const AcceptEncoding = "gzip, deflate, br, zstd"
+
+// DecodedBody returns a reader of the response body, undoing each
+// Content-Encoding in turn, as data arrives. The caller still closes
+// resp.Body.
+func DecodedBody(resp *http.Response) (io.Reader, error) {
+	return Decode(resp.Body, resp.Header.Values("Content-Encoding"))
+}
+
+// Decode undoes the content codings listed in Content-Encoding header
+// values, which were applied in order, so are undone in reverse.
+func Decode(r io.Reader, encodings []string) (io.Reader, error) {
+	codings := splitCodings(encodings)
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		switch codings[i] {
+		case "identity":
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = newDeflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			var d *zstd.Decoder
+			d, err = zstd.NewReader(r)
+			if err == nil {
+				r = d.IOReadCloser()
+			}
+		default:
+			err = fmt.Errorf("unsupported content encoding %q", codings[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Encode applies a single content coding to data, to compress a request
+func Encode(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		var err error
+		if w, err = zstd.NewWriter(&buf); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func splitCodings(values []string) []string {
+	var codings []string
+	for _, v := range values {
+		for _, c := range strings.Split(v, ",") {
+			if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+				codings = append(codings, c)
+			}
+		}
+	}
+	return codings
+}
+
+// newDeflateReader reads zlib wrapped deflate, as the specification
+// says, or the raw deflate that many servers send instead.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == io.EOF || (err == nil && !isZlibHeader(header)) {
+		return flate.NewReader(br), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return zlib.NewReader(br)
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
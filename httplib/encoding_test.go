@@ -0,0 +1,126 @@
+package httplib
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/skunkwerks/gurl/hamac"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	data := []byte(strings.Repeat(`{"squirrel":"nuts"}`, 100))
+	for _, enc := range []string{"gzip", "deflate", "br", "zstd"} {
+		encoded, err := Encode(data, enc)
+		if err != nil {
+			t.Fatal(enc, err)
+		}
+		if bytes.Equal(encoded, data) {
+			t.Fatal(enc, "data not encoded")
+		}
+		r, err := Decode(bytes.NewReader(encoded), []string{enc})
+		if err != nil {
+			t.Fatal(enc, err)
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(enc, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Fatal(enc, "decoded data not match")
+		}
+	}
+}
+
+func TestDecodedBodyStacked(t *testing.T) {
+	data := []byte("hello squirrel")
+	gz, err := Encode(data, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := Encode(gz, "br")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip", "identity, BR"}},
+		Body:   io.NopCloser(bytes.NewReader(br)),
+	}
+	r, err := DecodedBody(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("decoded data not match", string(decoded))
+	}
+}
+
+func TestDecodeRawDeflate(t *testing.T) {
+	data := []byte("hello squirrel")
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write(data)
+	w.Close()
+	r, err := Decode(&buf, []string{"deflate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("decoded data not match", string(decoded))
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	if _, err := Decode(strings.NewReader(""), []string{"compress"}); err == nil {
+		t.Fatal("unsupported encoding accepted")
+	}
+	if _, err := Encode(nil, "compress"); err == nil {
+		t.Fatal("unsupported encoding accepted")
+	}
+}
+
+func TestCompressOnce(t *testing.T) {
+	data := `{"squirrel":"nuts"}`
+	req := Post("http://example.com/ingest").Body(data).Compress("gzip")
+	if err := req.SignBody(hamac.New("sha256:x-sig:squirrel")); err != nil {
+		t.Fatal(err)
+	}
+	signature := req.GetRequest().Header.Get("X-Sig")
+
+	var length int64
+	for i := 0; i < 2; i++ {
+		r, err := req.Prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && r.ContentLength != length {
+			t.Fatal("body compressed again", length, r.ContentLength)
+		}
+		length = r.ContentLength
+		encoded, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hamac.Verify(hamac.New("sha256:x-sig:squirrel"), encoded, []byte(signature)) {
+			t.Fatal("signature does not cover the compressed body", signature)
+		}
+		decoded, err := Decode(bytes.NewReader(encoded), []string{"gzip"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := io.ReadAll(decoded); string(got) != data {
+			t.Fatal("unexpected body", string(got))
+		}
+	}
+}
@@ -34,13 +34,11 @@ package httplib
 
 import (
 	"bytes"
-	"compress/gzip"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
 	"io"
 	"log"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
@@ -54,7 +52,7 @@ import (
 	"github.com/skunkwerks/gurl/hamac"
 )
 
-var defaultSetting = BeegoHttpSettings{false, "beegoServer", 60 * time.Second, 60 * time.Second, nil, nil, nil, false, true, true}
+var defaultSetting = BeegoHttpSettings{false, "beegoServer", 60 * time.Second, 60 * time.Second, nil, nil, nil, false, true, true, false}
 var defaultCookieJar http.CookieJar
 var settingMutex sync.Mutex
 
@@ -93,7 +91,7 @@ func NewBeegoRequest(rawurl, method string) *BeegoHttpRequest {
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 	}
-	return &BeegoHttpRequest{rawurl, &req, map[string]string{}, map[string]string{}, defaultSetting, &resp, nil, nil}
+	return &BeegoHttpRequest{rawurl, &req, nil, nil, nil, defaultSetting, &resp, nil, nil, nil, nil, "", false, false, "", nil}
 }
 
 // Get returns *BeegoHttpRequest with GET method.
@@ -131,20 +129,37 @@ type BeegoHttpSettings struct {
 	Proxy            func(*http.Request) (*url.URL, error)
 	Transport        http.RoundTripper
 	EnableCookie     bool
-	Gzip             bool
+	Gzip             bool // decode the response Content-Encoding
 	DumpBody         bool
+	// DisableCompression stops the transport asking for gzip when the
+	// request has no Accept-Encoding, such as when it was removed
+	DisableCompression bool
+}
+
+// field is a parameter or form field, which may be repeated, and
+// is sent in the order it was added.
+type field struct {
+	name, value string
 }
 
 // BeegoHttpRequest provides more useful methods for requesting one url than http.Request.
 type BeegoHttpRequest struct {
-	url     string
-	req     *http.Request
-	params  map[string]string
-	files   map[string]string
-	setting BeegoHttpSettings
-	resp    *http.Response
-	body    []byte
-	dump    []byte
+	url        string
+	req        *http.Request
+	query      []field
+	params     []field
+	parts      []Part
+	setting    BeegoHttpSettings
+	resp       *http.Response
+	body       []byte
+	dump       []byte
+	sigv4      *hamac.SigV4
+	msgsig     *hamac.MessageSigner
+	compress   string
+	compressed bool
+	prepared   bool
+	multipart  string
+	progress   func(total int64) io.Writer
 }
 
 // get request
@@ -152,6 +167,11 @@ func (b *BeegoHttpRequest) GetRequest() *http.Request {
 	return b.req
 }
 
+// GetSetting returns the request settings
+func (b *BeegoHttpRequest) GetSetting() BeegoHttpSettings {
+	return b.setting
+}
+
 // Change request settings
 func (b *BeegoHttpRequest) Setting(setting BeegoHttpSettings) *BeegoHttpRequest {
 	b.setting = setting
@@ -212,6 +232,29 @@ func (b *BeegoHttpRequest) Header(key, value string) *BeegoHttpRequest {
 	return b
 }
 
+// AddHeader adds a value to a header, rather than replacing it, so the
+// header is repeated.
+func (b *BeegoHttpRequest) AddHeader(key, value string) *BeegoHttpRequest {
+	b.req.Header.Add(key, value)
+	return b
+}
+
+// RemoveHeader removes a header, including User-Agent and
+// Accept-Encoding, which would otherwise be added when sending.
+func (b *BeegoHttpRequest) RemoveHeader(key string) *BeegoHttpRequest {
+	key = http.CanonicalHeaderKey(key)
+	b.req.Header.Del(key)
+	switch key {
+	case "User-Agent":
+		b.setting.UserAgent = ""
+		// net/http only leaves out its own User-Agent if it is empty
+		b.req.Header[key] = []string{""}
+	case "Accept-Encoding":
+		b.setting.DisableCompression = true
+	}
+	return b
+}
+
 // Set HOST
 func (b *BeegoHttpRequest) SetHost(host string) *BeegoHttpRequest {
 	b.req.Host = host
@@ -259,25 +302,140 @@ func (b *BeegoHttpRequest) SetProxy(proxy func(*http.Request) (*url.URL, error))
 	return b
 }
 
+// Query adds a parameter to the query string, whatever the method.
+// A repeated key adds another value, as in ?tag=a&tag=b.
+func (b *BeegoHttpRequest) Query(key, value string) *BeegoHttpRequest {
+	b.query = append(b.query, field{key, value})
+	return b
+}
+
 // Param adds query param in to request.
 // params build query string as ?key1=value1&key2=value2...
+// A repeated key adds another value, as in ?tag=a&tag=b.
+// For POST, PUT and PATCH, params are sent as a form body instead.
 func (b *BeegoHttpRequest) Param(key, value string) *BeegoHttpRequest {
-	b.params[key] = value
+	b.params = append(b.params, field{key, value})
 	return b
 }
 
+// PostFile adds the file as a part of a multipart form
 func (b *BeegoHttpRequest) PostFile(formname, filename string) *BeegoHttpRequest {
-	b.files[formname] = filename
+	return b.PostPart(Part{Name: formname, Path: filename})
+}
+
+// SignBody calculates the HMAC and appends the header to the request,
+// building any form or multipart body from params and parts first, as it
+// is sent. The body is re-injected into the request as ReadAll consumes it. A
+// body that can't be read is an error, rather than an unsigned request.
+func (b *BeegoHttpRequest) SignBody(mac hamac.Hmac) error {
+	return b.SignBodyAt(mac, time.Now())
+}
+
+// SignBodyAt signs the body as SignBody does, using t as the timestamp
+// for provider schemes that sign one, so captured payloads can be
+// replayed with identical signatures.
+func (b *BeegoHttpRequest) SignBodyAt(mac hamac.Hmac, t time.Time) error {
+	if err := b.buildBody(); err != nil {
+		return err
+	}
+	if err := b.compressBody(); err != nil {
+		return err
+	}
+	body, err := b.bodyReader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	// a file is signed as it is read, rather than read into memory
+	h, err := hamac.HeadersFrom(mac, body, t)
+	if err != nil {
+		return err
+	}
+	for k, v := range h {
+		b.req.Header[k] = v
+	}
+	return nil
+}
+
+// Resendable reports whether the body can be sent more than once, as it
+// can unless it is streamed from a pipe, such as stdin, and was not read
+// into memory to sign or compress it.
+func (b *BeegoHttpRequest) Resendable() (bool, error) {
+	if err := b.buildBody(); err != nil {
+		return false, err
+	}
+	if err := b.compressBody(); err != nil {
+		return false, err
+	}
+	return b.req.Body == nil || b.req.GetBody != nil, nil
+}
+
+// bodyReader returns a reader of the request body, such as to sign it.
+// A body that can be read again, such as a file, is left to be streamed
+// as it is sent, while any other is read into memory, and replaced.
+func (b *BeegoHttpRequest) bodyReader() (io.ReadCloser, error) {
+	if b.req.Body == nil {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if b.req.GetBody == nil {
+		body, err := io.ReadAll(b.req.Body)
+		if err != nil {
+			return nil, err
+		}
+		b.setBody(body)
+	}
+	return b.req.GetBody()
+}
+
+// readBody returns the request body, as read by bodyReader
+func (b *BeegoHttpRequest) readBody() ([]byte, error) {
+	if b.req.Body == nil {
+		return nil, nil
+	}
+	body, err := b.bodyReader()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// SignV4 signs the request with AWS Signature Version 4 as it is sent,
+// once the final URL, query string and body are known.
+func (b *BeegoHttpRequest) SignV4(v hamac.SigV4) *BeegoHttpRequest {
+	b.sigv4 = &v
 	return b
 }
 
-// SignBody calculates the HMAC and appends the header to the request
-// The body is re-injected into the request as ReadAll consumes it.
-func (b *BeegoHttpRequest) SignBody(mac hamac.Hmac) *BeegoHttpRequest {
-	body, _ := io.ReadAll(b.req.Body)
-	signature := string(hamac.Sign(mac, body))
-	b.req.Header.Set(mac.Header, signature)
-	b.req.Body = io.NopCloser(bytes.NewBuffer(body))
+// signV4 hashes the body, unless the payload is unsigned
+func (b *BeegoHttpRequest) signV4() error {
+	payload := hamac.UnsignedPayload
+	if !b.sigv4.Unsigned {
+		body, err := b.bodyReader()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		if payload, err = hamac.PayloadHashFrom(body); err != nil {
+			return err
+		}
+	}
+	hamac.SignV4(*b.sigv4, b.req, payload, time.Now())
+	return nil
+}
+
+// SignMessage adds HTTP Message Signature headers as the request is sent,
+// so that derived components like @target-uri cover the final URL.
+func (b *BeegoHttpRequest) SignMessage(s hamac.MessageSigner) *BeegoHttpRequest {
+	b.msgsig = &s
+	return b
+}
+
+// Compress encodes the request body with gzip, deflate, br or zstd,
+// once, when it is first signed or sent, so every signature covers the
+// compressed body, as it is sent.
+func (b *BeegoHttpRequest) Compress(encoding string) *BeegoHttpRequest {
+	b.compress = encoding
 	return b
 }
 
@@ -286,17 +444,64 @@ func (b *BeegoHttpRequest) SignBody(mac hamac.Hmac) *BeegoHttpRequest {
 func (b *BeegoHttpRequest) Body(data interface{}) *BeegoHttpRequest {
 	switch t := data.(type) {
 	case string:
-		bf := bytes.NewBufferString(t)
-		b.req.Body = io.NopCloser(bf)
-		b.req.ContentLength = int64(len(t))
+		b.setBody([]byte(t))
 	case []byte:
-		bf := bytes.NewBuffer(t)
-		b.req.Body = io.NopCloser(bf)
-		b.req.ContentLength = int64(len(t))
+		b.setBody(t)
+	case io.Reader:
+		b.setStream(t)
 	}
 	return b
 }
 
+// streamBody is a body streamed as it is sent, rather than held in memory
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
+
+// setStream streams the body from r. A regular file is sent with its
+// Content-Length, and can be read again to sign or resend it, while any
+// other stream has an unknown length, so it is sent chunked.
+func (b *BeegoHttpRequest) setStream(r io.Reader) {
+	b.req.ContentLength = -1
+	b.req.GetBody = nil
+	if f, ok := r.(*os.File); ok {
+		fi, err := f.Stat()
+		offset, serr := f.Seek(0, io.SeekCurrent)
+		if err == nil && serr == nil && fi.Mode().IsRegular() {
+			size := fi.Size() - offset
+			b.req.Body = streamBody{io.NewSectionReader(f, offset, size), io.NopCloser(nil)}
+			b.req.ContentLength = size
+			b.req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(io.NewSectionReader(f, offset, size)), nil
+			}
+			return
+		}
+	}
+	closer, ok := r.(io.Closer)
+	if !ok {
+		closer = io.NopCloser(nil)
+	}
+	b.req.Body = streamBody{r, closer}
+}
+
+// UploadProgress calls progress with the length of the body, -1 if it is
+// unknown, as the request is sent, and copies the body to the writer it
+// returns as the body is read.
+func (b *BeegoHttpRequest) UploadProgress(progress func(total int64) io.Writer) *BeegoHttpRequest {
+	b.progress = progress
+	return b
+}
+
+// setBody also sets GetBody, so the request can be cloned and resent
+func (b *BeegoHttpRequest) setBody(data []byte) {
+	b.req.Body = io.NopCloser(bytes.NewReader(data))
+	b.req.ContentLength = int64(len(data))
+	b.req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
 // JsonBody adds request raw body encoding by JSON.
 func (b *BeegoHttpRequest) JsonBody(obj interface{}) (*BeegoHttpRequest, error) {
 	if b.req.Body == nil && obj != nil {
@@ -305,64 +510,71 @@ func (b *BeegoHttpRequest) JsonBody(obj interface{}) (*BeegoHttpRequest, error)
 		if err := enc.Encode(obj); err != nil {
 			return b, err
 		}
-		b.req.Body = io.NopCloser(buf)
-		b.req.ContentLength = int64(buf.Len())
+		b.setBody(buf.Bytes())
 		b.req.Header.Set("Content-Type", "application/json")
 	}
 	return b, nil
 }
 
-func (b *BeegoHttpRequest) buildUrl(paramBody string) {
-	// build GET url with query string
-	if b.req.Method == "GET" && len(paramBody) > 0 {
-		if strings.Index(b.url, "?") != -1 {
-			b.url += "&" + paramBody
-		} else {
-			b.url = b.url + "?" + paramBody
+// buildUrl returns the url with the query string, which has the Query
+// parameters, followed by params unless they are sent as a form body,
+// as they are for POST, PUT and PATCH.
+func (b *BeegoHttpRequest) buildUrl(paramBody string) string {
+	query := encodeFields(b.query)
+	if !b.withBody() && len(paramBody) > 0 {
+		if len(query) > 0 {
+			query += "&"
 		}
-		return
-	}
-
-	// build POST/PUT/PATCH url and body
-	if (b.req.Method == "POST" || b.req.Method == "PUT" || b.req.Method == "PATCH") && b.req.Body == nil {
-		// with files
-		if len(b.files) > 0 {
-			pr, pw := io.Pipe()
-			bodyWriter := multipart.NewWriter(pw)
-			go func() {
-				for formname, filename := range b.files {
-					fileWriter, err := bodyWriter.CreateFormFile(formname, filename)
-					if err != nil {
-						log.Fatal(err)
-					}
-					fh, err := os.Open(filename)
-					if err != nil {
-						log.Fatal(err)
-					}
-					//iocopy
-					_, err = io.Copy(fileWriter, fh)
-					fh.Close()
-					if err != nil {
-						log.Fatal(err)
-					}
-				}
-				for k, v := range b.params {
-					bodyWriter.WriteField(k, v)
-				}
-				bodyWriter.Close()
-				pw.Close()
-			}()
-			b.Header("Content-Type", bodyWriter.FormDataContentType())
-			b.req.Body = io.NopCloser(pr)
-			return
+		query += paramBody
+	}
+	rawurl := b.url
+	if len(query) > 0 {
+		if strings.Index(rawurl, "?") != -1 {
+			rawurl += "&" + query
+		} else {
+			rawurl = rawurl + "?" + query
 		}
+	}
+	return rawurl
+}
+
+// withBody reports whether params are sent as the body of the request
+func (b *BeegoHttpRequest) withBody() bool {
+	return b.req.Method == "POST" || b.req.Method == "PUT" || b.req.Method == "PATCH"
+}
+
+// buildBody builds the POST, PUT or PATCH body from params and parts,
+// unless there is a body, so it is built once, and is signed and
+// compressed as it is sent
+func (b *BeegoHttpRequest) buildBody() error {
+	if !b.withBody() || b.req.Body != nil {
+		return nil
+	}
+	// with parts
+	if len(b.parts) > 0 || b.multipart != "" {
+		return b.multipartBody()
+	}
 
-		// with params
-		if len(paramBody) > 0 {
-			b.Header("Content-Type", "application/x-www-form-urlencoded")
-			b.Body(paramBody)
+	// with params
+	if len(b.params) > 0 {
+		b.Header("Content-Type", "application/x-www-form-urlencoded")
+		b.Body(encodeFields(b.params))
+	}
+	return nil
+}
+
+// encodeFields encodes fields as a query string or form, in order
+func encodeFields(fields []field) string {
+	var buf bytes.Buffer
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte('&')
 		}
+		buf.WriteString(url.QueryEscape(f.name))
+		buf.WriteByte('=')
+		buf.WriteString(url.QueryEscape(f.value))
 	}
+	return buf.String()
 }
 
 func (b *BeegoHttpRequest) getResponse() (*http.Response, error) {
@@ -377,36 +589,17 @@ func (b *BeegoHttpRequest) getResponse() (*http.Response, error) {
 	return resp, nil
 }
 
-func (b *BeegoHttpRequest) SendOut() (*http.Response, error) {
-	var paramBody string
-	if len(b.params) > 0 {
-		var buf bytes.Buffer
-		for k, v := range b.params {
-			buf.WriteString(url.QueryEscape(k))
-			buf.WriteByte('=')
-			buf.WriteString(url.QueryEscape(v))
-			buf.WriteByte('&')
-		}
-		paramBody = buf.String()
-		paramBody = paramBody[0 : len(paramBody)-1]
-	}
-
-	b.buildUrl(paramBody)
-	url, err := url.Parse(b.url)
-	if err != nil {
-		return nil, err
-	}
-
-	b.req.URL = url
-
+// client builds an http.Client from the request settings
+func (b *BeegoHttpRequest) client() *http.Client {
 	trans := b.setting.Transport
 
 	if trans == nil {
 		// create default transport
 		trans = &http.Transport{
-			TLSClientConfig: b.setting.TlsClientConfig,
-			Proxy:           b.setting.Proxy,
-			Dial:            TimeoutDialer(b.setting.ConnectTimeout, b.setting.ReadWriteTimeout),
+			TLSClientConfig:    b.setting.TlsClientConfig,
+			Proxy:              b.setting.Proxy,
+			Dial:               TimeoutDialer(b.setting.ConnectTimeout, b.setting.ReadWriteTimeout),
+			DisableCompression: b.setting.DisableCompression,
 		}
 	} else {
 		// if b.transport is *http.Transport then set the settings.
@@ -420,6 +613,9 @@ func (b *BeegoHttpRequest) SendOut() (*http.Response, error) {
 			if t.Dial == nil {
 				t.Dial = TimeoutDialer(b.setting.ConnectTimeout, b.setting.ReadWriteTimeout)
 			}
+			if b.setting.DisableCompression {
+				t.DisableCompression = true
+			}
 		}
 	}
 
@@ -431,23 +627,133 @@ func (b *BeegoHttpRequest) SendOut() (*http.Response, error) {
 		jar = defaultCookieJar
 	}
 
-	client := &http.Client{
+	return &http.Client{
 		Transport: trans,
 		Jar:       jar,
 	}
+}
+
+// Do sends req with the same transport, proxy, TLS and cookie settings
+// as this request, such as to reconnect a stream with a cloned request.
+func (b *BeegoHttpRequest) Do(req *http.Request) (*http.Response, error) {
+	return b.client().Do(req)
+}
+
+func (b *BeegoHttpRequest) SendOut() (*http.Response, error) {
+	req, err := b.Prepare()
+	if err != nil {
+		return nil, err
+	}
+	return b.client().Do(req)
+}
+
+// Prepare finalises the request as SendOut would send it, building the
+// URL, adding the User-Agent, and applying any signatures, so it can be
+// sent by other means, such as a WebSocket handshake. It may be called
+// again to resend the request: the body is compressed only once, and
+// read again when it can be, while a stream, such as stdin, can only be
+// sent once. Upload progress is reported for the first send only.
+func (b *BeegoHttpRequest) Prepare() (*http.Request, error) {
+	url, err := url.Parse(b.buildUrl(encodeFields(b.params)))
+	if err != nil {
+		return nil, err
+	}
+
+	b.req.URL = url
+
+	if err := b.buildBody(); err != nil {
+		return nil, err
+	}
+	if err := b.compressBody(); err != nil {
+		return nil, err
+	}
+	// a request sent again, such as by -bench, sends its body again
+	resent := b.prepared
+	if resent && b.req.GetBody != nil {
+		body, err := b.req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		b.req.Body = body
+	}
+	b.prepared = true
 
 	if b.setting.UserAgent != "" && b.req.Header.Get("User-Agent") == "" {
 		b.req.Header.Set("User-Agent", b.setting.UserAgent)
 	}
 
+	if b.sigv4 != nil {
+		if err := b.signV4(); err != nil {
+			return nil, err
+		}
+	}
+	if b.msgsig != nil {
+		body, err := b.bodyReader()
+		if err != nil {
+			return nil, err
+		}
+		// a resent request replaces its own earlier signature
+		err = hamac.SignMessageFrom(*b.msgsig, b.req, body, time.Now())
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if b.setting.ShowDebug {
-		dump, err := httputil.DumpRequest(b.req, b.setting.DumpBody)
+		// a compressed body is not shown, as it is binary, nor a streamed
+		// one, which would have to be read into memory
+		_, stream := b.req.Body.(streamBody)
+		dump, err := httputil.DumpRequest(b.req, b.setting.DumpBody && b.compress == "" && !stream)
 		if err != nil {
 			println(err.Error())
 		}
 		b.dump = dump
 	}
-	return client.Do(b.req)
+	if b.progress != nil && b.req.Body != nil && !resent {
+		w := b.progress(b.req.ContentLength)
+		b.req.Body = streamBody{io.TeeReader(b.req.Body, w), b.req.Body}
+	}
+	return b.req, nil
+}
+
+// compressBody compresses the body as Compress asked, only the first
+// time it is called, so a request that is signed, or sent again, is not
+// compressed twice
+func (b *BeegoHttpRequest) compressBody() error {
+	if b.compress == "" || b.compressed || b.req.Body == nil {
+		return nil
+	}
+	body, err := b.readBody()
+	if err != nil {
+		return err
+	}
+	encoded, err := Encode(body, b.compress)
+	if err != nil {
+		return err
+	}
+	b.setBody(encoded)
+	b.req.Header.Set("Content-Encoding", b.compress)
+	b.compressed = true
+	return nil
+}
+
+// WireRequest prepares the request and returns it as it would be written
+// to the connection, without sending it.
+func (b *BeegoHttpRequest) WireRequest() ([]byte, error) {
+	req, err := b.Prepare()
+	if err != nil {
+		return nil, err
+	}
+	wire, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+	// the dump is written by a default transport, which asks for gzip
+	if b.setting.DisableCompression && req.Header.Get("Accept-Encoding") == "" {
+		wire = bytes.Replace(wire, []byte("Accept-Encoding: gzip\r\n"), nil, 1)
+	}
+	return wire, nil
 }
 
 // String returns the body string in response.
@@ -475,8 +781,8 @@ func (b *BeegoHttpRequest) Bytes() ([]byte, error) {
 		return nil, nil
 	}
 	defer resp.Body.Close()
-	if b.setting.Gzip && resp.Header.Get("Content-Encoding") == "gzip" {
-		reader, err := gzip.NewReader(resp.Body)
+	if b.setting.Gzip {
+		reader, err := DecodedBody(resp)
 		if err != nil {
 			return nil, err
 		}
@@ -507,7 +813,13 @@ func (b *BeegoHttpRequest) ToFile(filename string) error {
 		return nil
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(f, resp.Body)
+	var body io.Reader = resp.Body
+	if b.setting.Gzip {
+		if body, err = DecodedBody(resp); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(f, body)
 	return err
 }
 
@@ -537,13 +849,33 @@ func (b *BeegoHttpRequest) Response() (*http.Response, error) {
 }
 
 // TimeoutDialer returns functions of connection dialer with timeout settings for http.Transport Dial field.
+// The read-write timeout is renewed on each read and write, so it limits
+// idle time, and long-lived streams stay open while data keeps arriving.
 func TimeoutDialer(cTimeout time.Duration, rwTimeout time.Duration) func(net, addr string) (c net.Conn, err error) {
 	return func(netw, addr string) (net.Conn, error) {
 		conn, err := net.DialTimeout(netw, addr, cTimeout)
 		if err != nil {
 			return nil, err
 		}
-		conn.SetDeadline(time.Now().Add(rwTimeout))
-		return conn, nil
+		if rwTimeout <= 0 {
+			return conn, nil
+		}
+		return &idleTimeoutConn{conn, rwTimeout}, nil
 	}
 }
+
+// idleTimeoutConn pushes its deadline back on every read and write
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(p []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(p)
+}
+
+func (c *idleTimeoutConn) Write(p []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(p)
+}
@@ -15,10 +15,17 @@
 package httplib
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"io"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/skunkwerks/gurl/hamac"
 )
 
 func TestResponse(t *testing.T) {
@@ -199,7 +206,7 @@ func TestToFile(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer os.Remove(f)
-	b, err := io.ReadFile(f)
+	b, err := os.ReadFile(f)
 	if n := strings.Index(string(b), "origin"); n == -1 {
 		t.Fatal(err)
 	}
@@ -214,3 +221,304 @@ func TestHeader(t *testing.T) {
 	}
 	t.Log(str)
 }
+
+func TestPrepare(t *testing.T) {
+	req := Get("ws://example.com/socket")
+	req.Param("token", "a b")
+	req.SetUserAgent("gurl")
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL.String() != "ws://example.com/socket?token=a+b" {
+		t.Fatal("query string not added to the url", r.URL)
+	}
+	if r.Header.Get("User-Agent") != "gurl" {
+		t.Fatal("User-Agent not set", r.Header)
+	}
+}
+
+func TestWireRequest(t *testing.T) {
+	req := Post("http://example.com/post")
+	req.Param("name", "gurl")
+	req.SetUserAgent("gurl")
+	wire, err := req.WireRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := string(wire)
+	t.Log(str)
+	if !strings.HasPrefix(str, "POST /post HTTP/1.1\r\nHost: example.com\r\n") {
+		t.Fatal("unexpected request line", str)
+	}
+	if !strings.Contains(str, "Content-Length: 9\r\n") {
+		t.Fatal("Content-Length not set", str)
+	}
+	if !strings.HasSuffix(str, "\r\n\r\nname=gurl") {
+		t.Fatal("body not written", str)
+	}
+}
+
+func TestWireRequestSigned(t *testing.T) {
+	mac := hamac.New("sha256:x-sig:squirrel")
+	req := Post("http://example.com/post").Param("a", "b").Param("c", "d")
+	if err := req.SignBody(mac); err != nil {
+		t.Fatal(err)
+	}
+	wire, err := req.WireRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := string(wire)
+	if !strings.Contains(str, "\r\nX-Sig: sha256=7aee79305f944181334c505c835e54a5ffde4486d75da3b42ecfa5a663c666e3\r\n") {
+		t.Fatal("unexpected signature", str)
+	}
+	if !strings.HasSuffix(str, "\r\n\r\na=b&c=d") {
+		t.Fatal("body not written", str)
+	}
+
+	req = Post("http://example.com/post").Param("a", "b").PostFile("f", "httplib_test.go")
+	if err := req.SignBody(mac); err != nil {
+		t.Fatal(err)
+	}
+	wire, err = req.WireRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(wire)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hamac.Verify(mac, body, []byte(r.Header.Get("X-Sig"))) {
+		t.Fatal("signature does not cover the multipart body", r.Header.Get("X-Sig"))
+	}
+}
+
+func TestRemoveHeader(t *testing.T) {
+	req := Get("http://example.com/get")
+	req.SetUserAgent("gurl")
+	req.Header("X-Test", "1")
+	req.RemoveHeader("x-test").RemoveHeader("User-Agent").RemoveHeader("Accept-Encoding")
+	wire, err := req.WireRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := string(wire)
+	t.Log(str)
+	for _, header := range []string{"X-Test", "User-Agent", "Accept-Encoding"} {
+		if strings.Contains(str, header) {
+			t.Fatal(header, "not removed", str)
+		}
+	}
+}
+
+func TestRepeatedParams(t *testing.T) {
+	req := Get("http://example.com/get?a=1")
+	req.Param("tag", "x").Param("b", "2").Param("tag", "y")
+	req.Header("X-Tag", "a").AddHeader("X-Tag", "b")
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL.RawQuery != "a=1&tag=x&b=2&tag=y" {
+		t.Fatal("params not kept in order", r.URL.RawQuery)
+	}
+	if len(r.Header["X-Tag"]) != 2 {
+		t.Fatal("header not repeated", r.Header)
+	}
+
+	req = Post("http://example.com/post")
+	req.Param("tag", "x").Param("tag", "y")
+	r, err = req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "tag=x&tag=y" {
+		t.Fatal("form fields not repeated", string(data))
+	}
+}
+
+func TestQuery(t *testing.T) {
+	req := NewBeegoRequest("http://example.com/items?x=1", "DELETE")
+	req.Query("id", "5").Param("force", "true")
+	for i := 0; i < 2; i++ {
+		r, err := req.Prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.URL.RawQuery != "x=1&id=5&force=true" {
+			t.Fatal("unexpected query string", r.URL.RawQuery)
+		}
+	}
+
+	req = Post("http://example.com/items")
+	req.Query("page", "2").Param("name", "gurl")
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL.RawQuery != "page=2" {
+		t.Fatal("unexpected query string", r.URL.RawQuery)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "name=gurl" {
+		t.Fatal("params not sent as a form", string(data))
+	}
+}
+
+func TestStreamBody(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("streamed body"); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, io.SeekStart)
+
+	var total int64
+	var sent strings.Builder
+	req := Post("http://example.com/upload").Body(f)
+	req.UploadProgress(func(n int64) io.Writer {
+		total = n
+		return &sent
+	})
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ContentLength != 13 || r.GetBody == nil {
+		t.Fatal("a file should be sent with its length", r.ContentLength)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "streamed body" || total != 13 || sent.String() != "streamed body" {
+		t.Fatal("unexpected body or progress", string(data), total, sent.String())
+	}
+
+	r, err = Post("http://example.com/upload").Body(strings.NewReader("chunked")).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ContentLength != -1 {
+		t.Fatal("a stream should have an unknown length", r.ContentLength)
+	}
+}
+
+func TestSignBodyError(t *testing.T) {
+	failed := errors.New("failed")
+	req := Post("http://example.com/upload").Body(iotest.ErrReader(failed))
+	if err := req.SignBody(hamac.New("sha256:x-sig:squirrel")); !errors.Is(err, failed) {
+		t.Fatal("expected the read error", err)
+	}
+	if req.GetRequest().Header.Get("X-Sig") != "" {
+		t.Fatal("unexpected signature", req.GetRequest().Header)
+	}
+}
+
+func TestSignBuiltBody(t *testing.T) {
+	mac := hamac.New("sha256:x-sig:squirrel")
+	form := func() *BeegoHttpRequest {
+		return Post("http://example.com/post").Param("a", "b").Param("c", "d")
+	}
+	testCases := []struct {
+		name string
+		req  *BeegoHttpRequest
+		want string
+	}{
+		{name: "form", req: form(), want: "sha256=7aee79305f944181334c505c835e54a5ffde4486d75da3b42ecfa5a663c666e3"},
+		{name: "compressed form", req: form().Compress("gzip")},
+		{name: "multipart", req: form().PostPart(Part{Name: "f", Filename: "f.txt", Body: strings.NewReader("nuts")})},
+		{name: "multipart file", req: form().PostFile("f", "httplib_test.go")},
+		{name: "multipart stream", req: form().PostPart(Part{Name: "f", Body: io.LimitReader(strings.NewReader("nuts"), 4)})},
+	}
+
+	for _, tc := range testCases {
+		if err := tc.req.SignBody(mac); err != nil {
+			t.Fatal(tc.name, err)
+		}
+		signature := tc.req.GetRequest().Header.Get("X-Sig")
+		if tc.want != "" && signature != tc.want {
+			t.Errorf("%v: signature %v, wanted %v", tc.name, signature, tc.want)
+		}
+		r, err := tc.req.Prepare()
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if len(body) == 0 || !hamac.Verify(mac, body, []byte(signature)) {
+			t.Errorf("%v: signature %v does not cover the body %q", tc.name, signature, body)
+		}
+	}
+}
+
+func TestSignStreamedFile(t *testing.T) {
+	f, err := os.Open("httplib_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := os.ReadFile("httplib_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := Put("http://example.com/upload").Body(f)
+	req.SignV4(hamac.SigV4{AccessKey: "AKIDEXAMPLE", SecretKey: "secret", Region: "us-east-1", Service: "s3"})
+	req.SignMessage(hamac.MessageSigner{Algorithm: "hmac-sha256", Key: []byte("squirrel")})
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != hamac.PayloadHash(data) {
+		t.Fatal("unexpected payload hash", got)
+	}
+	if got := r.Header.Get("Content-Digest"); got != hamac.ContentDigest(data) {
+		t.Fatal("unexpected content digest", got)
+	}
+	// the file is still streamed, rather than read into memory to sign it
+	if _, ok := r.Body.(streamBody); !ok {
+		t.Fatalf("body replaced by %T", r.Body)
+	}
+}
+
+func TestResendable(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  *BeegoHttpRequest
+		want bool
+	}{
+		{name: "no body", req: Get("http://example.com/"), want: true},
+		{name: "form", req: Post("http://example.com/").Param("a", "b"), want: true},
+		{name: "stream", req: Post("http://example.com/").Body(io.LimitReader(strings.NewReader("a"), 1))},
+		{name: "compressed stream", req: Post("http://example.com/").Body(io.LimitReader(strings.NewReader("a"), 1)).Compress("gzip"), want: true},
+		{name: "multipart", req: Post("http://example.com/").PostPart(Part{Name: "a", Body: strings.NewReader("a")}), want: true},
+		{name: "multipart stream", req: Post("http://example.com/").PostPart(Part{Name: "a", Body: io.LimitReader(strings.NewReader("a"), 1)})},
+	}
+	for _, tc := range testCases {
+		got, err := tc.req.Resendable()
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%v: resendable %v", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package httplib
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Part is a part of a multipart body, read from the file at Path, or
+// from Body if there is no Path. Filename defaults to the base of Path,
+// and ContentType to one guessed from the Filename extension.
+type Part struct {
+	Name        string
+	Filename    string
+	ContentType string
+	Path        string
+	Body        io.Reader
+}
+
+// PostPart adds a part to a multipart body, after any params, which are
+// sent as form fields.
+func (b *BeegoHttpRequest) PostPart(part Part) *BeegoHttpRequest {
+	b.parts = append(b.parts, part)
+	return b
+}
+
+// Multipart sends params and parts as a multipart body of the subtype,
+// such as mixed or related, rather than form-data, even without parts.
+func (b *BeegoHttpRequest) Multipart(subtype string) *BeegoHttpRequest {
+	b.multipart = subtype
+	return b
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// header returns the MIME header of the part, in a body of subtype
+func (p Part) header(subtype string) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	filename := p.Filename
+	if filename == "" && p.Path != "" {
+		filename = filepath.Base(p.Path)
+	}
+
+	disposition := "form-data"
+	if subtype != "form-data" {
+		disposition = "inline"
+		if filename != "" {
+			disposition = "attachment"
+		}
+	}
+	if p.Name != "" || subtype == "form-data" {
+		disposition += fmt.Sprintf(`; name="%s"`, quoteEscaper.Replace(p.Name))
+	}
+	if filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+	}
+	h.Set("Content-Disposition", disposition)
+	// parts of a related body are referred to by their Content-ID
+	if subtype == "related" && p.Name != "" {
+		h.Set("Content-ID", "<"+p.Name+">")
+	}
+
+	contentType := p.ContentType
+	if contentType == "" && filename != "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+	if contentType == "" && subtype != "form-data" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	return h
+}
+
+// multipartBody streams the params and parts through a pipe as the body
+// is read. Files are opened first, so a missing one is reported before
+// anything is sent, and any later error fails the read of the body. A
+// body whose parts can all be read again, such as files and strings, can
+// also be read again with GetBody, to sign or resend it as it is
+// streamed, with the same boundary.
+func (b *BeegoHttpRequest) multipartBody() error {
+	subtype := b.multipart
+	if subtype == "" {
+		subtype = "form-data"
+	}
+	for _, p := range b.parts {
+		if p.Path == "" {
+			continue
+		}
+		f, err := os.Open(p.Path)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+
+	// a part read again is first rewound to where it started
+	offsets := make([]int64, len(b.parts))
+	rereadable := true
+	for i, p := range b.parts {
+		if p.Path != "" || p.Body == nil {
+			continue
+		}
+		s, ok := p.Body.(io.Seeker)
+		if !ok {
+			rereadable = false
+			break
+		}
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			rereadable = false
+			break
+		}
+		offsets[i] = offset
+	}
+
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	open := func() (io.ReadCloser, error) {
+		parts := b.multipartParts()
+		if rereadable {
+			for i, p := range b.parts {
+				if p.Path == "" && p.Body != nil {
+					if _, err := p.Body.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		files := make([]*os.File, len(parts))
+		for i, p := range parts {
+			if p.Path == "" {
+				continue
+			}
+			f, err := os.Open(p.Path)
+			if err != nil {
+				for _, f := range files[:i] {
+					if f != nil {
+						f.Close()
+					}
+				}
+				return nil, err
+			}
+			files[i] = f
+		}
+
+		pr, pw := io.Pipe()
+		bodyWriter := multipart.NewWriter(pw)
+		if err := bodyWriter.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+		go func() {
+			err := writeParts(bodyWriter, parts, files, subtype)
+			for _, f := range files {
+				if f != nil {
+					f.Close()
+				}
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+
+	params := map[string]string{"boundary": boundary}
+	// a related body names the type of its first, root, part
+	if parts := b.multipartParts(); subtype == "related" && len(parts) > 0 {
+		if root := parts[0].header(subtype).Get("Content-Type"); root != "" {
+			params["type"], _, _ = mime.ParseMediaType(root)
+		}
+	}
+	b.Header("Content-Type", mime.FormatMediaType("multipart/"+subtype, params))
+	body := &lazyReader{open: open}
+	b.req.Body = streamBody{body, body}
+	b.req.ContentLength = -1
+	b.req.GetBody = nil
+	if rereadable {
+		b.req.GetBody = open
+	}
+	return nil
+}
+
+// multipartParts returns the params, as parts, followed by the parts
+func (b *BeegoHttpRequest) multipartParts() []Part {
+	parts := make([]Part, 0, len(b.params)+len(b.parts))
+	for _, p := range b.params {
+		parts = append(parts, Part{Name: p.name, Body: strings.NewReader(p.value)})
+	}
+	return append(parts, b.parts...)
+}
+
+// lazyReader opens its reader when it is first read, so that a body read
+// through GetBody before it is sent, such as to sign it, is not read ahead
+type lazyReader struct {
+	open func() (io.ReadCloser, error)
+	r    io.ReadCloser
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil {
+		r, err := l.open()
+		if err != nil {
+			return 0, err
+		}
+		l.r = r
+	}
+	return l.r.Read(p)
+}
+
+func (l *lazyReader) Close() error {
+	if l.r == nil {
+		return nil
+	}
+	return l.r.Close()
+}
+
+func writeParts(w *multipart.Writer, parts []Part, files []*os.File, subtype string) error {
+	for i, p := range parts {
+		pw, err := w.CreatePart(p.header(subtype))
+		if err != nil {
+			return err
+		}
+		var r io.Reader = files[i]
+		if files[i] == nil {
+			r = p.Body
+		}
+		if r == nil {
+			continue
+		}
+		if _, err := io.Copy(pw, r); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,87 @@
+package httplib
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMultipartForm(t *testing.T) {
+	req := Post("http://example.com/upload")
+	req.Param("name", "gurl")
+	req.PostPart(Part{Name: "meta", ContentType: "application/json", Body: strings.NewReader(`{"a":1}`)})
+	req.PostPart(Part{Name: "photo", Filename: "a.png", Body: strings.NewReader("PNG")})
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediatype != "multipart/form-data" {
+		t.Fatal("unexpected Content-Type", r.Header.Get("Content-Type"))
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	want := []struct{ name, filename, contentType, body string }{
+		{"name", "", "", "gurl"},
+		{"meta", "", "application/json", `{"a":1}`},
+		{"photo", "a.png", "image/png", "PNG"},
+	}
+	for _, w := range want {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(p)
+		if p.FormName() != w.name || p.FileName() != w.filename ||
+			p.Header.Get("Content-Type") != w.contentType || string(data) != w.body {
+			t.Fatal("unexpected part", p.Header, string(data))
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatal("expected the end of the body", err)
+	}
+}
+
+func TestMultipartRelated(t *testing.T) {
+	req := Post("http://example.com/upload")
+	req.Multipart("related")
+	req.PostPart(Part{Name: "root", ContentType: "application/json", Body: strings.NewReader("{}")})
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediatype, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediatype != "multipart/related" || params["type"] != "application/json" {
+		t.Fatal("unexpected Content-Type", r.Header.Get("Content-Type"))
+	}
+	p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Header.Get("Content-ID") != "<root>" {
+		t.Fatal("unexpected Content-ID", p.Header)
+	}
+}
+
+func TestMultipartErrors(t *testing.T) {
+	req := Post("http://example.com/upload")
+	req.PostFile("file", "does-not-exist")
+	if _, err := req.Prepare(); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+
+	failed := errors.New("failed")
+	req = Post("http://example.com/upload")
+	req.PostPart(Part{Name: "file", Body: iotest.ErrReader(failed)})
+	r, err := req.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r.Body); !errors.Is(err, failed) {
+		t.Fatal("expected the read error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// itemSeparators are matched longest first, at the first position in a
+// request item where any of them appears
+var itemSeparators = []string{":=@", "==", ":=", "=@", ":@", ":", ";", "=", "@"}
+
+// requestItem is a request item split at its separator
+type requestItem struct {
+	key, sep, value string
+}
+
+// isData reports whether the item goes into the request body, or into
+// the query string of a GET request.
+func (it requestItem) isData() bool {
+	switch it.sep {
+	case "=", ":=", "=@", ":=@", "@":
+		return true
+	}
+	return false
+}
+
+// parseItem splits a request item at its first separator. A backslash
+// escapes a separator character in the key, so foo\==bar is the data
+// field foo= rather than a query parameter. Header; is the only item
+// with the ; separator, for a header with an empty value.
+func parseItem(arg string) (requestItem, bool) {
+	var key strings.Builder
+	for i := 0; i < len(arg); i++ {
+		if arg[i] == '\\' && i+1 < len(arg) && strings.IndexByte(":=@;", arg[i+1]) >= 0 {
+			i++
+			key.WriteByte(arg[i])
+			continue
+		}
+		for _, sep := range itemSeparators {
+			if sep == ";" && i != len(arg)-1 {
+				continue
+			}
+			if strings.HasPrefix(arg[i:], sep) {
+				return requestItem{key.String(), sep, arg[i+len(sep):]}, true
+			}
+		}
+		key.WriteByte(arg[i])
+	}
+	return requestItem{}, false
+}
+
+// parseItems parses every request item, failing on any that has no
+// separator, rather than leaving it out of the request. Variables are
+// expanded in keys and values once the item is split, so a value may
+// hold any separator.
+func parseItems(args []string) []requestItem {
+	items := make([]requestItem, 0, len(args))
+	for _, arg := range args {
+		it, ok := parseItem(arg)
+		if !ok {
+			log.Fatalf("invalid request item %q, use a separator such as =, :=, ==, : or @", arg)
+		}
+		var err error
+		if it.key, err = expandVars(it.key); err != nil {
+			log.Fatalf("request item %q: %v", arg, err)
+		}
+		if it.value, err = expandVars(it.value); err != nil {
+			log.Fatalf("request item %q: %v", arg, err)
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// filePart makes the part for a key@path item. The path may be followed
+// by ;type=TYPE, whose parameters follow it, and ;filename=NAME options,
+// and the path - reads stdin, which is then not sent as the body.
+func filePart(name, value string) httplib.Part {
+	part := httplib.Part{Name: name, Path: value}
+	start := -1
+	for _, opt := range []string{";type=", ";filename="} {
+		if i := strings.Index(value, opt); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	if start >= 0 {
+		part.Path = value[:start]
+		var last *string
+		for _, opt := range strings.Split(value[start+1:], ";") {
+			if v := strings.TrimPrefix(opt, "type="); v != opt {
+				part.ContentType, last = v, &part.ContentType
+			} else if v := strings.TrimPrefix(opt, "filename="); v != opt {
+				part.Filename, last = v, &part.Filename
+			} else if last != nil {
+				*last += ";" + opt
+			}
+		}
+	}
+	if part.Path == "-" {
+		if ignoreStdin {
+			log.Fatalf("%s@- reads stdin, which -ignore-stdin leaves out", name)
+		}
+		part.Path = ""
+		part.Body = os.Stdin
+		if stdin != nil {
+			part.Body = stdin
+		}
+		stdin = nil
+		if part.ContentType == "" {
+			part.ContentType = "application/octet-stream"
+		}
+	}
+	return part
+}
+
+// readItemFile reads the file named by an @ item
+func readItemFile(path string) []byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal("Read File ", path, " ", err)
+	}
+	return content
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseItem(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name   string
+		input  string
+		want   requestItem
+		wantOk bool
+		isData bool
+	}{
+		{name: "data", input: "a=b", want: requestItem{"a", "=", "b"}, wantOk: true, isData: true},
+		{name: "empty data", input: "a=", want: requestItem{"a", "=", ""}, wantOk: true, isData: true},
+		{name: "raw json", input: "n:=1", want: requestItem{"n", ":=", "1"}, wantOk: true, isData: true},
+		{name: "raw json file", input: "n:=@a.json", want: requestItem{"n", ":=@", "a.json"}, wantOk: true, isData: true},
+		{name: "data file", input: "a=@a.txt", want: requestItem{"a", "=@", "a.txt"}, wantOk: true, isData: true},
+		{name: "file", input: "f@a.png", want: requestItem{"f", "@", "a.png"}, wantOk: true, isData: true},
+		{name: "query", input: "q==go", want: requestItem{"q", "==", "go"}, wantOk: true},
+		{name: "query not data", input: "a==b=c", want: requestItem{"a", "==", "b=c"}, wantOk: true},
+		{name: "header", input: "X-A:1", want: requestItem{"X-A", ":", "1"}, wantOk: true},
+		{name: "header removal", input: "X-A:", want: requestItem{"X-A", ":", ""}, wantOk: true},
+		{name: "header file", input: "X-A:@tok.txt", want: requestItem{"X-A", ":@", "tok.txt"}, wantOk: true},
+		{name: "empty header", input: "X-A;", want: requestItem{"X-A", ";", ""}, wantOk: true},
+		{name: "semicolon inside", input: "a;b=c", want: requestItem{"a;b", "=", "c"}, wantOk: true, isData: true},
+		{name: "leftmost separator", input: "a=b:c", want: requestItem{"a", "=", "b:c"}, wantOk: true, isData: true},
+		{name: "header with url value", input: "Referer:http://x.org/?a=b", want: requestItem{"Referer", ":", "http://x.org/?a=b"}, wantOk: true},
+		{name: "escaped equals", input: `foo\==bar`, want: requestItem{"foo=", "=", "bar"}, wantOk: true, isData: true},
+		{name: "escaped colon", input: `a\:b:c`, want: requestItem{"a:b", ":", "c"}, wantOk: true},
+		{name: "escaped at", input: `me\@x=1`, want: requestItem{"me@x", "=", "1"}, wantOk: true, isData: true},
+		{name: "other backslash kept", input: `a\b=c`, want: requestItem{`a\b`, "=", "c"}, wantOk: true, isData: true},
+		{name: "no separator", input: "plain", wantOk: false},
+		{name: "only escaped separator", input: `a\=b`, wantOk: false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := parseItem(tc.input)
+		if ok != tc.wantOk {
+			t.Errorf("%v: parsed %v, wanted %v", tc.name, ok, tc.wantOk)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !cmp.Equal(tc.want, got, cmp.AllowUnexported(requestItem{})) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got, cmp.AllowUnexported(requestItem{})))
+		}
+		if got.isData() != tc.isData {
+			t.Errorf("%v: isData %v", tc.name, got.isData())
+		}
+	}
+}
+
+func TestParseItems(t *testing.T) {
+	t.Parallel()
+	got := parseItems([]string{"a=b", "q==1", "X-A:1"})
+	want := []requestItem{{"a", "=", "b"}, {"q", "==", "1"}, {"X-A", ":", "1"}}
+	if !cmp.Equal(want, got, cmp.AllowUnexported(requestItem{})) {
+		t.Errorf("diff %v", cmp.Diff(want, got, cmp.AllowUnexported(requestItem{})))
+	}
+}
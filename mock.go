@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mockRoute is one canned response, as written in a route file or
+// converted from a recorded exchange.
+type mockRoute struct {
+	Method   string      `json:"method" yaml:"method"`
+	Path     string      `json:"path" yaml:"path"`
+	URL      string      `json:"url" yaml:"url"`
+	Status   int         `json:"status" yaml:"status"`
+	Headers  mockHeaders `json:"headers" yaml:"headers"`
+	Body     interface{} `json:"body" yaml:"body"`
+	BodyFile string      `json:"bodyFile" yaml:"bodyFile"`
+	Delay    string      `json:"delay" yaml:"delay"`
+
+	segments []string
+	query    url.Values
+	body     *template.Template
+	raw      []byte
+	delay    time.Duration
+}
+
+// mockRequest is what body templates see of the incoming request
+type mockRequest struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   url.Values
+	Headers http.Header
+	Body    string
+	JSON    interface{}
+}
+
+// mockHeaders are the headers of a response, each with a value, or a
+// list of them for a header that is repeated, such as Set-Cookie
+type mockHeaders http.Header
+
+func (h *mockHeaders) UnmarshalJSON(data []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	return h.set(values)
+}
+
+func (h *mockHeaders) UnmarshalYAML(node *yaml.Node) error {
+	var values map[string]interface{}
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	return h.set(values)
+}
+
+func (h *mockHeaders) set(values map[string]interface{}) error {
+	*h = make(mockHeaders)
+	for k, v := range values {
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		for _, item := range list {
+			switch item.(type) {
+			case []interface{}, map[string]interface{}, nil:
+				return fmt.Errorf("header %s: %v is not a value", k, item)
+			}
+			http.Header(*h).Add(k, fmt.Sprint(item))
+		}
+	}
+	return nil
+}
+
+type mockServer struct {
+	routes []*mockRoute
+}
+
+// loadMock reads routes from a YAML or JSON route file, whose bodies are
+// templates, or from a HAR or JSONL recording, whose bodies are replayed
+// as they are.
+func loadMock(path string) *mockServer {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal("Read mock file ", err)
+	}
+
+	var routes []*mockRoute
+	templated := false
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".har":
+		routes, err = parseHAR(data)
+	case ".jsonl", ".ndjson":
+		routes, err = parseJSONL(data)
+	default:
+		templated = true
+		err = yaml.Unmarshal(data, &routes)
+	}
+	if err != nil {
+		log.Fatalf("Parse mock file %s: %v", path, err)
+	}
+
+	m := &mockServer{}
+	for i, r := range routes {
+		if err := r.compile(filepath.Dir(path), templated); err != nil {
+			log.Fatalf("mock route %d %s %s: %v", i+1, r.Method, r.Path, err)
+		}
+		m.routes = append(m.routes, r)
+	}
+	return m
+}
+
+func (r *mockRoute) compile(dir string, templated bool) error {
+	if r.Path == "" && r.URL != "" {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return err
+		}
+		r.Path = u.Path
+		if u.RawQuery != "" {
+			r.Path += "?" + u.RawQuery
+		}
+	}
+	// a query in the path must be in the request too
+	if i := strings.IndexByte(r.Path, '?'); i >= 0 {
+		query, err := url.ParseQuery(r.Path[i+1:])
+		if err != nil {
+			return err
+		}
+		r.Path, r.query = r.Path[:i], query
+	}
+	if r.Path == "" {
+		r.Path = "/"
+	}
+	r.Method = strings.ToUpper(r.Method)
+	r.segments = strings.Split(strings.Trim(r.Path, "/"), "/")
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+
+	if r.Delay != "" {
+		d, err := time.ParseDuration(r.Delay)
+		if err != nil {
+			return err
+		}
+		r.delay = d
+	}
+
+	switch body := r.Body.(type) {
+	case nil:
+	case string:
+		r.raw = []byte(body)
+	default:
+		// structured bodies in route files are sent as JSON
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r.raw = raw
+		if http.Header(r.Headers).Get("Content-Type") == "" {
+			if r.Headers == nil {
+				r.Headers = make(mockHeaders)
+			}
+			http.Header(r.Headers).Set("Content-Type", "application/json")
+		}
+	}
+	if r.BodyFile != "" {
+		file := r.BodyFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		r.raw = raw
+	}
+
+	if templated && bytes.Contains(r.raw, []byte("{{")) {
+		t, err := template.New(r.Path).Parse(string(r.raw))
+		if err != nil {
+			return err
+		}
+		r.body = t
+	}
+	return nil
+}
+
+// match reports whether the route serves req, and the values captured
+// by {name} segments. A final * segment matches the rest of the path,
+// and each parameter of the route's query must have the same values in
+// the request, which may have others.
+func (r *mockRoute) match(req *http.Request) (map[string]string, bool) {
+	if r.Method != "" && r.Method != "*" && r.Method != req.Method {
+		return nil, false
+	}
+	query := req.URL.Query()
+	for k, want := range r.query {
+		got := query[k]
+		if len(got) != len(want) {
+			return nil, false
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				return nil, false
+			}
+		}
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	params := make(map[string]string)
+	for i, s := range r.segments {
+		if s == "*" && i == len(r.segments)-1 {
+			params["*"] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			params[s[1:len(s)-1]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, len(segments) == len(r.segments)
+}
+
+func (m *mockServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for _, r := range m.routes {
+		params, ok := r.match(req)
+		if !ok {
+			continue
+		}
+
+		body := r.raw
+		if r.body != nil {
+			var err error
+			if body, err = r.render(req, params); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if r.delay > 0 {
+			time.Sleep(r.delay)
+		}
+		for k, v := range r.Headers {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(r.Status)
+		w.Write(body)
+		return
+	}
+	http.Error(w, fmt.Sprintf("no mock route for %s %s", req.Method, req.URL.Path), http.StatusNotFound)
+}
+
+func (r *mockRoute) render(req *http.Request, params map[string]string) ([]byte, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := mockRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Params:  params,
+		Query:   req.URL.Query(),
+		Headers: req.Header,
+		Body:    string(body),
+	}
+	// a body that is not JSON just leaves .JSON empty
+	json.Unmarshal(body, &data.JSON)
+
+	var out bytes.Buffer
+	if err := r.body.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// parseJSONL reads one route per line, in the same form as a route file
+func parseJSONL(data []byte) ([]*mockRoute, error) {
+	var routes []*mockRoute
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r mockRoute
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		routes = append(routes, &r)
+	}
+	return routes, scanner.Err()
+}
+
+// har is the subset of the HTTP Archive format needed to replay responses
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+func parseHAR(data []byte) ([]*mockRoute, error) {
+	var h har
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	var routes []*mockRoute
+	for _, e := range h.Log.Entries {
+		r := &mockRoute{
+			Method:  e.Request.Method,
+			URL:     e.Request.URL,
+			Status:  e.Response.Status,
+			Headers: make(mockHeaders),
+			Body:    e.Response.Content.Text,
+		}
+		if e.Response.Content.Encoding == "base64" {
+			raw, err := base64.StdEncoding.DecodeString(e.Response.Content.Text)
+			if err != nil {
+				return nil, err
+			}
+			r.Body = string(raw)
+		}
+		for _, header := range e.Response.Headers {
+			// the recorded content is already decoded, and reframed here
+			switch strings.ToLower(header.Name) {
+			case "content-length", "content-encoding", "transfer-encoding":
+				continue
+			}
+			if strings.HasPrefix(header.Name, ":") {
+				continue
+			}
+			http.Header(r.Headers).Add(header.Name, header.Value)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
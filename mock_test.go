@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockCase struct {
+	name, method, path string
+	status             int
+	body               string
+	header             http.Header
+}
+
+// testMock serves the mock file and checks the response to each request
+func testMock(t *testing.T, name, data string, testCases []mockCase) {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(loadMock(path))
+	defer ts.Close()
+
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, ts.URL+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("%v: status %v, wanted %v", tc.name, resp.StatusCode, tc.status)
+		}
+		if tc.body != "" && string(body) != tc.body {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.body, string(body)))
+		}
+		for k, want := range tc.header {
+			if got := resp.Header.Values(k); !cmp.Equal(want, got) {
+				t.Errorf("%v: %v diff %v", tc.name, k, cmp.Diff(want, got))
+			}
+		}
+	}
+}
+
+func TestMockRoutes(t *testing.T) {
+	routes := `
+- method: GET
+  path: /users/{id}
+  headers:
+    content-type: application/json
+    Set-Cookie: [a=1, b=2]
+  body: '{"id": "{{.Params.id}}", "q": "{{.Query.Get "q"}}"}'
+- method: POST
+  path: /users
+  status: 201
+  body:
+    created: true
+- path: /search?q=go
+  body: go
+- path: /search
+  body: anything
+- method: "*"
+  path: /static/*
+  body: 'static {{index .Params "*"}}'
+`
+	testMock(t, "routes.yaml", routes, []mockCase{
+		{name: "capture", method: "GET", path: "/users/42?q=x", status: 200, body: `{"id": "42", "q": "x"}`,
+			header: http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"a=1", "b=2"}}},
+		{name: "method", method: "DELETE", path: "/users/42", status: 404},
+		{name: "structured body", method: "POST", path: "/users", status: 201, body: `{"created":true}`,
+			header: http.Header{"Content-Type": {"application/json"}}},
+		{name: "query", method: "GET", path: "/search?q=go&page=2", status: 200, body: "go"},
+		{name: "other query", method: "GET", path: "/search?q=rust", status: 200, body: "anything"},
+		{name: "rest of the path", method: "PUT", path: "/static/css/a.css", status: 200, body: "static css/a.css"},
+		{name: "too short", method: "GET", path: "/users", status: 404},
+		{name: "too long", method: "GET", path: "/users/42/posts", status: 404},
+	})
+}
+
+func TestMockHAR(t *testing.T) {
+	recording := `{"log": {"entries": [
+  {"request": {"method": "GET", "url": "https://api.example.com/items?page=2"},
+   "response": {"status": 200, "headers": [
+     {"name": "Content-Type", "value": "application/json"},
+     {"name": "Content-Encoding", "value": "gzip"},
+     {"name": "Set-Cookie", "value": "a=1"},
+     {"name": "Set-Cookie", "value": "b=2"}],
+    "content": {"text": "[3, 4]"}}},
+  {"request": {"method": "GET", "url": "https://api.example.com/items"},
+   "response": {"status": 200, "headers": [],
+    "content": {"text": "WzEsIDJd", "encoding": "base64"}}}
+]}}`
+	testMock(t, "api.har", recording, []mockCase{
+		{name: "recorded query", method: "GET", path: "/items?page=2", status: 200, body: "[3, 4]",
+			header: http.Header{"Set-Cookie": {"a=1", "b=2"}, "Content-Encoding": nil}},
+		{name: "without the query", method: "GET", path: "/items", status: 200, body: "[1, 2]"},
+		{name: "other query", method: "GET", path: "/items?page=3", status: 200, body: "[1, 2]"},
+		{name: "method", method: "POST", path: "/items", status: 404},
+	})
+}
+
+func TestMockHeaderErrors(t *testing.T) {
+	for _, data := range []string{`{"headers": {"X-A": {"b": 1}}}`, `{"headers": {"X-A": null}}`} {
+		if _, err := parseJSONL([]byte(data)); err == nil || !strings.Contains(err.Error(), "X-A") {
+			t.Errorf("%v: unexpected error %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// itemPath splits the key of a request item, such as user[name] or
+// items[0][id], into object keys and array indexes, where -1 appends to
+// an array, as for tags[]. A key that starts with a bracket addresses
+// the body itself, so []=a sends an array. A backslash escapes brackets.
+func itemPath(key string) ([]interface{}, error) {
+	var path []interface{}
+	var seg strings.Builder
+	i := 0
+	for ; i < len(key) && key[i] != '['; i++ {
+		if key[i] == '\\' && i+1 < len(key) && (key[i+1] == '[' || key[i+1] == ']' || key[i+1] == '\\') {
+			i++
+		}
+		seg.WriteByte(key[i])
+	}
+	if i == len(key) || seg.Len() > 0 {
+		path = append(path, seg.String())
+	}
+
+	for i < len(key) {
+		if key[i] != '[' {
+			return nil, fmt.Errorf("%q: expected [ at %q", key, key[i:])
+		}
+		seg.Reset()
+		closed := false
+		for i++; i < len(key); i++ {
+			if key[i] == '\\' && i+1 < len(key) && (key[i+1] == '[' || key[i+1] == ']' || key[i+1] == '\\') {
+				i++
+			} else if key[i] == ']' {
+				closed = true
+				i++
+				break
+			}
+			seg.WriteByte(key[i])
+		}
+		if !closed {
+			return nil, fmt.Errorf("%q: missing ]", key)
+		}
+		s := seg.String()
+		if s == "" {
+			path = append(path, -1)
+		} else if n, err := strconv.Atoi(s); err == nil && n >= 0 && s[0] != '+' {
+			path = append(path, n)
+		} else {
+			path = append(path, s)
+		}
+	}
+	return path, nil
+}
+
+// maxArrayPadding limits the nulls that pad an array before an index,
+// so a mistyped index is an error rather than a huge body
+const maxArrayPadding = 1000
+
+// setItem sets the value at the key's path in the request items, making
+// the objects and arrays along it as needed, and returns the new root.
+func setItem(root interface{}, key string, value interface{}) (interface{}, error) {
+	path, err := itemPath(key)
+	if err != nil {
+		return nil, err
+	}
+	return setPath(root, path, value, key)
+}
+
+func setPath(node interface{}, path []interface{}, value interface{}, key string) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	switch seg := path[0].(type) {
+	case string:
+		obj, ok := node.(map[string]interface{})
+		if node == nil {
+			obj, ok = make(map[string]interface{}), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%q: %q is set in a value that is not an object", key, seg)
+		}
+		v, err := setPath(obj[seg], path[1:], value, key)
+		if err != nil {
+			return nil, err
+		}
+		obj[seg] = v
+		return obj, nil
+	case int:
+		arr, ok := node.([]interface{})
+		if node == nil {
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%q: an index is set in a value that is not an array", key)
+		}
+		if seg < 0 {
+			seg = len(arr)
+		}
+		if seg > len(arr)+maxArrayPadding {
+			return nil, fmt.Errorf("%q: index %d is more than %d past the end of the array", key, seg, maxArrayPadding)
+		}
+		// gaps are filled with null, as in JavaScript
+		for len(arr) <= seg {
+			arr = append(arr, nil)
+		}
+		v, err := setPath(arr[seg], path[1:], value, key)
+		if err != nil {
+			return nil, err
+		}
+		arr[seg] = v
+		return arr, nil
+	}
+	return node, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestItemPath(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		key     string
+		want    []interface{}
+		wantErr bool
+	}{
+		{name: "plain", key: "name", want: []interface{}{"name"}},
+		{name: "object", key: "user[name]", want: []interface{}{"user", "name"}},
+		{name: "append", key: "tags[]", want: []interface{}{"tags", -1}},
+		{name: "index", key: "items[0][id]", want: []interface{}{"items", 0, "id"}},
+		{name: "top level array", key: "[]", want: []interface{}{-1}},
+		{name: "top level object", key: "[a]", want: []interface{}{"a"}},
+		{name: "escaped brackets", key: `a\[b\]`, want: []interface{}{"a[b]"}},
+		{name: "escaped in segment", key: `a[b\]c]`, want: []interface{}{"a", "b]c"}},
+		{name: "signed index is a key", key: "a[+1]", want: []interface{}{"a", "+1"}},
+		{name: "missing bracket", key: "a[b", wantErr: true},
+		{name: "text after segment", key: "a[b]c", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := itemPath(tc.key)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !tc.wantErr && !cmp.Equal(tc.want, got) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestSetItem(t *testing.T) {
+	t.Parallel()
+	type item struct {
+		key   string
+		value interface{}
+	}
+	testCases := []struct {
+		name    string
+		items   []item
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "nested objects",
+			items: []item{{"user[name]", "gurl"}, {"user[age]", 3.0}},
+			want:  map[string]interface{}{"user": map[string]interface{}{"name": "gurl", "age": 3.0}},
+		},
+		{
+			name:  "append",
+			items: []item{{"tags[]", "a"}, {"tags[]", "b"}},
+			want:  map[string]interface{}{"tags": []interface{}{"a", "b"}},
+		},
+		{
+			name:  "array of objects",
+			items: []item{{"items[0][id]", 1.0}, {"items[1][id]", 2.0}},
+			want: map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"id": 1.0},
+				map[string]interface{}{"id": 2.0},
+			}},
+		},
+		{
+			name:  "padded with null",
+			items: []item{{"a[2]", "x"}},
+			want:  map[string]interface{}{"a": []interface{}{nil, nil, "x"}},
+		},
+		{
+			name:  "top level array",
+			items: []item{{"[]", "a"}, {"[]", "b"}},
+			want:  []interface{}{"a", "b"},
+		},
+		{
+			name:    "index too far past the end",
+			items:   []item{{"a[999999999]", "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "field of an array",
+			items:   []item{{"a[]", "x"}, {"a[b]", "y"}},
+			wantErr: true,
+		},
+		{
+			name:    "index of an object",
+			items:   []item{{"a[b]", "x"}, {"a[0]", "y"}},
+			wantErr: true,
+		},
+		{
+			name:    "field of a string",
+			items:   []item{{"a", "x"}, {"a[b]", "y"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		var root interface{}
+		var err error
+		for _, it := range tc.items {
+			if root, err = setItem(root, it.key, it.value); err != nil {
+				break
+			}
+		}
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !tc.wantErr && !cmp.Equal(tc.want, root) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, root))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/skunkwerks/gurl/httplib"
+	"golang.org/x/term"
+)
+
+// interactive output shows the whole exchange, as selected by -print,
+// while output for a pipe or file is the response body alone.
+var interactive bool
+
+// setupOutput decides, once, whether output is interactive and whether
+// it is colored. Colors are turned off by emptying the palette, so the
+// same rendering serves both cases. With -color=auto, NO_COLOR and a
+// dumb terminal turn colors off, as described at https://no-color.org,
+// while -color=always forces colors into a pipe. Only a terminal is
+// interactive, whatever the colors, so a pipe still gets the body alone.
+func setupOutput(mode, style string) {
+	terminal := isTerminal(os.Stdout)
+	var color bool
+	switch mode {
+	case "auto":
+		color = terminal && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	case "always":
+		color = true
+	case "never":
+	default:
+		log.Fatalf("unknown color mode %q, use auto, always or never", mode)
+	}
+	// old Windows consoles can't interpret escape sequences
+	if color && !enableVirtualTerminal(os.Stdout) && mode == "auto" {
+		color = false
+	}
+
+	setStyle(style)
+	if !color {
+		palette = nil
+	}
+	interactive = terminal
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+func printResponseHeader(res *http.Response) {
+	fmt.Println(Color(res.Proto, Magenta), Color(res.Status, Green))
+	for k, v := range res.Header {
+		fmt.Println(Color(k, Gray), ":", Color(strings.Join(v, " "), Cyan))
+	}
+	fmt.Println("")
+}
+
+// printExchange prints the request and response, as selected by -print,
+// or only the response body when output is not interactive.
+func printExchange(httpreq *httplib.BeegoHttpRequest, res *http.Response) {
+	if !interactive {
+		if streaming(res) {
+			streamResponse(httpreq, res)
+			return
+		}
+		body := formatResponseBody(res, httpreq, pretty)
+		body = ColorfulResponse(body, res.Header.Get("Content-Type"))
+		if _, err := os.Stdout.WriteString(body); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var dumpHeader, dumpBody []byte
+	dump := httpreq.DumpRequest()
+	dps := strings.Split(string(dump), "\n")
+	for i, line := range dps {
+		if len(strings.Trim(line, "\r\n ")) == 0 {
+			dumpHeader = []byte(strings.Join(dps[:i], "\n"))
+			dumpBody = []byte(strings.Join(dps[i:], "\n"))
+			break
+		}
+	}
+	if printOption&printReqHeader == printReqHeader {
+		fmt.Println(ColorfulRequest(string(dumpHeader)))
+		fmt.Println("")
+	}
+	if printOption&printReqBody == printReqBody {
+		if string(dumpBody) != "\r\n" {
+			fmt.Println(string(dumpBody))
+			fmt.Println("")
+		}
+	}
+	if printOption&printRespHeader == printRespHeader {
+		printResponseHeader(res)
+	}
+	if printOption&printRespBody == printRespBody {
+		if streaming(res) {
+			fmt.Println("")
+			streamResponse(httpreq, res)
+			return
+		}
+		body := formatResponseBody(res, httpreq, pretty)
+		fmt.Println(ColorfulResponse(body, res.Header.Get("Content-Type")))
+	}
+}
+
+// printOffline prints the request as it would be written to the
+// connection. On a terminal, its body is formatted like a response
+// body, and otherwise it is written exactly, as it is to -offline.out.
+func printOffline(httpreq *httplib.BeegoHttpRequest) {
+	wire, err := httpreq.WireRequest()
+	if err != nil {
+		log.Fatal("can't build the request ", err)
+	}
+	if offlineOut != "" {
+		if err := os.WriteFile(offlineOut, wire, 0666); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if !interactive {
+		if _, err := os.Stdout.Write(wire); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	req := httpreq.GetRequest()
+	header, body := string(wire), ""
+	if i := strings.Index(header, "\r\n\r\n"); i >= 0 {
+		header, body = header[:i], header[i+4:]
+	}
+	fmt.Println(ColorfulRequest(strings.ReplaceAll(header, "\r\n", "\n")))
+	if body == "" {
+		return
+	}
+	fmt.Println("")
+	if req.Header.Get("Content-Encoding") != "" {
+		fmt.Println(Color(fmt.Sprintf("%s body, %d bytes", req.Header.Get("Content-Encoding"), len(body)), Gray))
+		return
+	}
+	contentType := req.Header.Get("Content-Type")
+	if str, err := formatBody([]byte(body), contentType, pretty); err == nil {
+		body = ColorfulResponse(str, contentType)
+	}
+	fmt.Println(strings.TrimRight(body, "\r\n"))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetupOutput(t *testing.T) {
+	defer func(p theme, i bool) { palette, interactive = p, i }(palette, interactive)
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	if isTerminal(os.Stdout) {
+		t.Skip("output is a terminal")
+	}
+	testCases := []struct {
+		mode  string
+		color bool
+	}{
+		{mode: "auto"},
+		{mode: "always", color: true},
+		{mode: "never"},
+	}
+	for _, tc := range testCases {
+		setupOutput(tc.mode, "default")
+		if (palette != nil) != tc.color {
+			t.Errorf("%v: colored %v, wanted %v", tc.mode, palette != nil, tc.color)
+		}
+		// colors don't change what is printed
+		if interactive {
+			t.Errorf("%v: interactive output into a pipe", tc.mode)
+		}
+	}
+}
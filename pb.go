@@ -11,6 +11,8 @@ import (
 const (
 	DEFAULT_REFRESH_RATE = time.Millisecond * 200
 	FORMAT               = "[=>-]"
+	// uploadProgressMin is the smallest body that shows upload progress
+	uploadProgressMin = 1 << 20
 )
 
 type ProgressBar struct {
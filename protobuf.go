@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	protoOnce  sync.Once
+	protoFiles *protoregistry.Files
+	protoErr   error
+)
+
+// loadProto reads the -proto descriptor set once. A .proto file is first
+// compiled into one by protoc, which must be on the PATH.
+func loadProto() (*protoregistry.Files, error) {
+	protoOnce.Do(func() {
+		if protoFile == "" {
+			return
+		}
+		path := protoFile
+		if strings.EqualFold(filepath.Ext(path), ".proto") {
+			out, err := os.CreateTemp("", "gurl-*.protoset")
+			if err != nil {
+				protoErr = err
+				return
+			}
+			out.Close()
+			defer os.Remove(out.Name())
+			cmd := exec.Command("protoc", "--include_imports", "--descriptor_set_out="+out.Name(),
+				"-I", filepath.Dir(path), path)
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				protoErr = fmt.Errorf("protoc %s: %v", path, err)
+				return
+			}
+			path = out.Name()
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			protoErr = err
+			return
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			protoErr = fmt.Errorf("descriptor set %s: %v", protoFile, err)
+			return
+		}
+		protoFiles, protoErr = protodesc.NewFiles(&set)
+	})
+	return protoFiles, protoErr
+}
+
+// protoMessageType finds the message type named by -proto.message, or
+// by the proto or messageType parameter of the Content-Type.
+func protoMessageType(contentType string) (protoreflect.MessageDescriptor, error) {
+	files, err := loadProto()
+	if err != nil || files == nil {
+		return nil, err
+	}
+	name := protoMessage
+	if name == "" {
+		_, params, _ := mime.ParseMediaType(contentType)
+		name = params["proto"]
+		if name == "" {
+			name = params["messagetype"]
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("message %s: %v", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return md, nil
+}
+
+// decodeProtobuf decodes with the message type, if one is known, and
+// otherwise shows the raw fields by number, as protoc --decode_raw does.
+func decodeProtobuf(body []byte, contentType string) ([]byte, error) {
+	md, err := protoMessageType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if md == nil {
+		fields, err := decodeRawProtobuf(body)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(fields)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	// protojson varies its whitespace on purpose
+	var out bytes.Buffer
+	if err := json.Compact(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encodeProtobuf encodes request items, gathered as JSON, as the message
+// type given by -proto.message.
+func encodeProtobuf(data []byte) ([]byte, error) {
+	md, err := protoMessageType("")
+	if err != nil {
+		return nil, err
+	}
+	if md == nil {
+		return nil, fmt.Errorf("-format=protobuf needs -proto and -proto.message")
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// rawFields are the fields of a message decoded without its type, in
+// the order they were first seen, with repeated numbers gathered.
+type rawFields struct {
+	numbers []protowire.Number
+	values  map[protowire.Number][]interface{}
+}
+
+func (f rawFields) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, num := range f.numbers {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(strconv.Quote(strconv.Itoa(int(num))))
+		out.WriteByte(':')
+		var v interface{} = f.values[num]
+		if len(f.values[num]) == 1 {
+			v = f.values[num][0]
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(data)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func decodeRawProtobuf(b []byte) (rawFields, error) {
+	fields := rawFields{values: make(map[protowire.Number][]interface{})}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fields, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			v, n = protowire.ConsumeFixed32(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			var data []byte
+			data, n = protowire.ConsumeBytes(b)
+			v = rawBytes(data)
+		case protowire.StartGroupType:
+			var data []byte
+			data, n = protowire.ConsumeGroup(num, b)
+			if n >= 0 {
+				v, _ = decodeRawProtobuf(data)
+			}
+		default:
+			return fields, fmt.Errorf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			return fields, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if _, ok := fields.values[num]; !ok {
+			fields.numbers = append(fields.numbers, num)
+		}
+		fields.values[num] = append(fields.values[num], v)
+	}
+	return fields, nil
+}
+
+// rawBytes guesses whether a length delimited field is text, a nested
+// message, or neither, which is shown as base64.
+func rawBytes(data []byte) interface{} {
+	if utf8.Valid(data) && strings.IndexFunc(string(data), func(r rune) bool {
+		return !unicode.IsPrint(r) && !unicode.IsSpace(r)
+	}) < 0 {
+		return string(data)
+	}
+	if nested, err := decodeRawProtobuf(data); err == nil && len(nested.numbers) > 0 {
+		return nested
+	}
+	return data
+}
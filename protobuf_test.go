@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeRawProtobuf(t *testing.T) {
+	t.Parallel()
+	varint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+	bytesField := func(b []byte, num protowire.Number, v []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+	nested := varint(nil, 1, 7)
+
+	testCases := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "varint",
+			input: varint(nil, 1, 150),
+			want:  `{"1":150}`,
+		},
+		{
+			name:  "fields in order seen",
+			input: varint(varint(nil, 2, 1), 1, 2),
+			want:  `{"2":1,"1":2}`,
+		},
+		{
+			name:  "repeated field",
+			input: varint(varint(varint(nil, 1, 1), 2, 0), 1, 2),
+			want:  `{"1":[1,2],"2":0}`,
+		},
+		{
+			name:  "fixed",
+			input: protowire.AppendFixed64(protowire.AppendTag(protowire.AppendFixed32(protowire.AppendTag(nil, 1, protowire.Fixed32Type), 5), 2, protowire.Fixed64Type), 6),
+			want:  `{"1":5,"2":6}`,
+		},
+		{
+			name:  "text",
+			input: bytesField(nil, 1, []byte("hello world\n")),
+			want:  `{"1":"hello world\n"}`,
+		},
+		{
+			name:  "nested message",
+			input: bytesField(nil, 3, nested),
+			want:  `{"3":{"1":7}}`,
+		},
+		{
+			name:  "binary",
+			input: bytesField(nil, 1, []byte{0xff, 0x00}),
+			want:  `{"1":"/wA="}`,
+		},
+		{
+			name:  "group",
+			input: protowire.AppendTag(append(protowire.AppendTag(nil, 4, protowire.StartGroupType), nested...), 4, protowire.EndGroupType),
+			want:  `{"4":{"1":7}}`,
+		},
+		{
+			name:    "truncated",
+			input:   bytesField(nil, 1, []byte("hello"))[:4],
+			wantErr: true,
+		},
+		{
+			name:    "unmatched end group",
+			input:   protowire.AppendTag(nil, 1, protowire.EndGroupType),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fields, err := decodeRawProtobuf(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		got, err := json.Marshal(fields)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%v: got %s, wanted %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeProtobufWithoutType(t *testing.T) {
+	if protoFile != "" {
+		t.Skip("-proto is set")
+	}
+	body := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 150)
+	got, err := decodeProtobuf(body, "application/x-protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"1":150}`; string(got) != want {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+	if _, err := encodeProtobuf([]byte(`{"a":1}`)); err == nil {
+		t.Errorf("encoded without a message type")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skunkwerks/gurl/hamac"
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// inspector prints every incoming request, as gurl prints outgoing ones,
+// optionally checks its HMAC signature, and then hands it to reply.
+type inspector struct {
+	mu    sync.Mutex
+	macs  []hamac.Hmac
+	reply http.Handler
+}
+
+// serve runs a local HTTP server on addr until it fails or is killed
+func serve(addr string, reply http.Handler) {
+	i := &inspector{
+		macs:  loadHmacs(),
+		reply: reply,
+	}
+	fmt.Printf("Listening on %s\n\n", addr)
+	log.Fatal(http.ListenAndServe(addr, i))
+}
+
+func (i *inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var verified error
+	if len(i.macs) > 0 {
+		verified = hamac.VerifyAny(i.macs, r.Header, body)
+	}
+	i.print(r, body, verified)
+
+	if verified != nil && hmacVerify {
+		http.Error(w, "signature "+verified.Error(), http.StatusUnauthorized)
+		return
+	}
+	i.reply.ServeHTTP(w, r)
+}
+
+// print writes the request out in one go, as requests arrive concurrently
+func (i *inspector) print(r *http.Request, body []byte, verified error) {
+	dump, err := httputil.DumpRequest(r, false)
+	if err != nil {
+		log.Println("can't dump request", err)
+		return
+	}
+	header := strings.TrimRight(string(dump), "\r\n")
+	contentType := r.Header.Get("Content-Type")
+	// the signature covers the body as sent, while it is shown decoded
+	shown := body
+	if encodings := r.Header.Values("Content-Encoding"); len(encodings) > 0 {
+		if dr, err := httplib.Decode(bytes.NewReader(body), encodings); err == nil {
+			if decoded, err := io.ReadAll(dr); err == nil {
+				shown = decoded
+			}
+		}
+	}
+	str, err := formatBody(shown, contentType, pretty)
+	if err != nil {
+		str = string(shown)
+	}
+	str = strings.TrimRight(str, "\r\n")
+
+	// -print=H and -print=B select the parts shown, as for sent requests
+	if printOption&(printReqHeader|printReqBody) == 0 {
+		return
+	}
+
+	var out strings.Builder
+	stamp := fmt.Sprintf("%s from %s", time.Now().Format(time.RFC3339), r.RemoteAddr)
+	fmt.Fprintln(&out, Color(stamp, Gray))
+	if printOption&printReqHeader == printReqHeader {
+		fmt.Fprintln(&out, ColorfulRequest(header))
+	}
+	if len(body) > 0 && printOption&printReqBody == printReqBody {
+		fmt.Fprintln(&out, "")
+		fmt.Fprintln(&out, ColorfulResponse(str, contentType))
+	}
+	if len(i.macs) > 0 {
+		fmt.Fprintln(&out, "")
+		status := "verified"
+		color := Green
+		if verified != nil {
+			status = verified.Error()
+			color = Red
+		}
+		fmt.Fprintf(&out, "HMAC %s: %s\n", i.macs[0].Header, Color(status, color))
+	}
+	fmt.Fprintln(&out, "")
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	os.Stdout.WriteString(out.String())
+}
+
+// cannedResponse replies to every request with the same status, headers
+// and body, as given by the -listen.* flags
+func cannedResponse(status int, headers []string, body string) http.Handler {
+	if strings.HasPrefix(body, "@") {
+		content, err := os.ReadFile(strings.TrimPrefix(body, "@"))
+		if err != nil {
+			log.Fatal("Read File", strings.TrimPrefix(body, "@"), err)
+		}
+		body = string(content)
+	}
+	h := make(http.Header)
+	for _, header := range headers {
+		kv := strings.SplitN(header, ":", 2)
+		if len(kv) != 2 {
+			log.Fatalf("response header %q must be Name:Value", header)
+		}
+		h.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range h {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skunkwerks/gurl/hamac"
+)
+
+func TestInspector(t *testing.T) {
+	mac := hamac.New("sha256:x-sig:squirrel")
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write([]byte(`{"a":1}`))
+	zw.Close()
+
+	testCases := []struct {
+		name     string
+		body     []byte
+		encoding string
+		signed   bool
+		verify   bool
+		want     int
+	}{
+		{name: "unsigned", body: []byte("a=b"), want: 202},
+		{name: "signed", body: []byte("a=b"), signed: true, verify: true, want: 202},
+		{name: "signed compressed", body: gzipped.Bytes(), encoding: "gzip", signed: true, verify: true, want: 202},
+		{name: "unsigned verified", body: []byte("a=b"), verify: true, want: 401},
+	}
+
+	defer func(v bool) { hmacVerify = v }(hmacVerify)
+	for _, tc := range testCases {
+		hmacVerify = tc.verify
+		ts := httptest.NewServer(&inspector{
+			macs:  []hamac.Hmac{mac},
+			reply: cannedResponse(202, []string{"X-A: 1", "X-A:2"}, "ok"),
+		})
+		req, err := http.NewRequest("POST", ts.URL, bytes.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.encoding != "" {
+			req.Header.Set("Content-Encoding", tc.encoding)
+		}
+		if tc.signed {
+			req.Header.Set("X-Sig", string(hamac.Sign(mac, tc.body)))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		ts.Close()
+
+		if resp.StatusCode != tc.want {
+			t.Errorf("%v: status %v, wanted %v: %s", tc.name, resp.StatusCode, tc.want, body)
+			continue
+		}
+		if tc.want != 202 {
+			continue
+		}
+		if string(body) != "ok" {
+			t.Errorf("%v: unexpected body %q", tc.name, body)
+		}
+		if want := []string{"1", "2"}; !cmp.Equal(want, resp.Header.Values("X-A")) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(want, resp.Header.Values("X-A")))
+		}
+	}
+}
+
+func TestCannedResponseBodyFile(t *testing.T) {
+	rec := httptest.NewRecorder()
+	cannedResponse(200, nil, "@server_test.go").ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !strings.HasPrefix(rec.Body.String(), "package main") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// defaultRetry is the reconnection delay until the server sets one
+const defaultRetry = 3 * time.Second
+
+// sseEvent is one dispatched Server-Sent Event
+type sseEvent struct {
+	id    string
+	event string
+	data  []string
+	retry string
+}
+
+// isEventStream reports whether the response is Server-Sent Events
+func isEventStream(res *http.Response) bool {
+	mediatype, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return mediatype == "text/event-stream"
+}
+
+// streamEvents prints each event as it arrives, rather than waiting for
+// the body to end. When the server closes the stream, the request is
+// resent with Last-Event-ID, after the retry delay the server asked for,
+// until it replies with anything but 200 and an event stream.
+func streamEvents(httpreq *httplib.BeegoHttpRequest, res *http.Response) {
+	retry := defaultRetry
+	lastID := ""
+	for {
+		if body, err := httplib.DecodedBody(res); err != nil {
+			log.Println("can't decode event stream", err)
+		} else {
+			lastID, retry = readEvents(body, lastID, retry)
+		}
+		res.Body.Close()
+
+		if !sseReconnect {
+			return
+		}
+		for {
+			notice := fmt.Sprintf("stream closed, reconnecting in %s", retry)
+			if lastID != "" {
+				notice += ", Last-Event-ID: " + lastID
+			}
+			printNotice(notice)
+			time.Sleep(retry)
+
+			var err error
+			res, err = resend(httpreq, res.Request, lastID)
+			if err == nil {
+				break
+			}
+			printNotice(err.Error())
+		}
+		if res.StatusCode != http.StatusOK || !isEventStream(res) {
+			printNotice("stream ended with " + res.Status)
+			res.Body.Close()
+			return
+		}
+	}
+}
+
+// resend clones the original request, as its body has been consumed
+func resend(httpreq *httplib.BeegoHttpRequest, orig *http.Request, lastID string) (*http.Response, error) {
+	req := orig.Clone(context.Background())
+	if orig.GetBody != nil {
+		body, err := orig.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	return httpreq.Do(req)
+}
+
+// printNotice writes to stderr, to keep piped events in the wire format
+func printNotice(notice string) {
+	fmt.Fprintf(os.Stderr, "%s\n\n", Color(notice, Gray))
+}
+
+// readEvents parses the stream as described in the HTML living standard,
+// printing each event, and returns the last event id and retry delay.
+func readEvents(r io.Reader, lastID string, retry time.Duration) (string, time.Duration) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	var ev sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if ev.data != nil || ev.event != "" || ev.id != "" || ev.retry != "" {
+				printEvent(ev)
+			}
+			ev = sseEvent{}
+			continue
+		}
+		// comments are used as keep-alives
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field = line[:i]
+			value = strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				ev.id = value
+				lastID = value
+			}
+		case "event":
+			ev.event = value
+		case "data":
+			ev.data = append(ev.data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				ev.retry = value
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		printNotice(err.Error())
+	}
+	return lastID, retry
+}
+
+// printEvent writes the event back out in its wire format, so plain
+// output can be piped on, pretty printing JSON data on a terminal.
+func printEvent(ev sseEvent) {
+	var out strings.Builder
+	field := func(name, value string, color uint8) {
+		fmt.Fprintf(&out, "%s: %s\n", Color(name, Gray), Color(value, color))
+	}
+
+	if ev.id != "" {
+		field("id", ev.id, Cyan)
+	}
+	if ev.event != "" {
+		field("event", ev.event, Magenta)
+	}
+	if ev.retry != "" {
+		field("retry", ev.retry, Cyan)
+	}
+	data := strings.Join(ev.data, "\n")
+	if interactive && ev.data != nil {
+		if str, err := formatBody([]byte(data), "application/json", pretty); err == nil && json.Valid([]byte(data)) {
+			fmt.Fprintf(&out, "%s: %s\n", Color("data", Gray), ColorfulJson(str))
+		} else {
+			for _, line := range ev.data {
+				field("data", line, Cyan)
+			}
+		}
+	} else {
+		for _, line := range ev.data {
+			fmt.Fprintf(&out, "data: %s\n", line)
+		}
+	}
+	fmt.Println(out.String())
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// captureStdout returns what f prints
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestReadEvents(t *testing.T) {
+	defer func(p theme, i bool) { palette, interactive = p, i }(palette, interactive)
+	palette, interactive = nil, false
+
+	testCases := []struct {
+		name      string
+		stream    string
+		lastID    string
+		want      string
+		wantID    string
+		wantRetry time.Duration
+	}{
+		{
+			name:      "data",
+			stream:    "data: hello\n\n",
+			want:      "data: hello\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "multiline data",
+			stream:    "data: a\ndata: b\n\n",
+			want:      "data: a\ndata: b\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "all fields",
+			stream:    "id: 7\nevent: update\nretry: 500\ndata: {\"a\":1}\n\n",
+			want:      "id: 7\nevent: update\nretry: 500\ndata: {\"a\":1}\n\n",
+			wantID:    "7",
+			wantRetry: 500 * time.Millisecond,
+		},
+		{
+			name:      "comments are skipped",
+			stream:    ": keep-alive\n\ndata: x\n\n",
+			want:      "data: x\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "no space after the colon",
+			stream:    "data:x\n\n",
+			want:      "data: x\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "field without a value",
+			stream:    "data\n\n",
+			want:      "data: \n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "last id is kept",
+			stream:    "data: x\n\n",
+			lastID:    "3",
+			want:      "data: x\n\n",
+			wantID:    "3",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "id with null is ignored",
+			stream:    "id: a\x00b\ndata: x\n\n",
+			lastID:    "3",
+			want:      "data: x\n\n",
+			wantID:    "3",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "invalid retry is ignored",
+			stream:    "retry: soon\ndata: x\n\n",
+			want:      "data: x\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "unknown fields are ignored",
+			stream:    "foo: bar\n\n",
+			wantRetry: defaultRetry,
+		},
+		{
+			name:      "unterminated event is not dispatched",
+			stream:    "id: 1\ndata: x\n\nid: 2\ndata: y\n",
+			want:      "id: 1\ndata: x\n\n",
+			wantID:    "2",
+			wantRetry: defaultRetry,
+		},
+	}
+
+	for _, tc := range testCases {
+		var id string
+		var retry time.Duration
+		got := captureStdout(t, func() {
+			id, retry = readEvents(strings.NewReader(tc.stream), tc.lastID, defaultRetry)
+		})
+		if got != tc.want {
+			t.Errorf("%v: printed %q, wanted %q", tc.name, got, tc.want)
+		}
+		if id != tc.wantID {
+			t.Errorf("%v: last id %q, wanted %q", tc.name, id, tc.wantID)
+		}
+		if retry != tc.wantRetry {
+			t.Errorf("%v: retry %v, wanted %v", tc.name, retry, tc.wantRetry)
+		}
+	}
+}
+
+func TestResend(t *testing.T) {
+	type request struct {
+		lastID string
+		body   string
+	}
+	var got []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.Header.Get("Last-Event-ID"), string(body)})
+	}))
+	defer ts.Close()
+
+	httpreq := httplib.Post(ts.URL).Body("subscribe")
+	res, err := httpreq.Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	for _, lastID := range []string{"", "42"} {
+		res, err := resend(httpreq, res.Request, lastID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	want := []request{{"", "subscribe"}, {"", "subscribe"}, {"42", "subscribe"}}
+	if len(got) != len(want) {
+		t.Fatalf("%d requests, wanted %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d: got %+v, wanted %+v", i, got[i], want[i])
+		}
+	}
+}
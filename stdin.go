@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+)
+
+// stdinBody returns stdin to send as the request body, or nil when it has
+// nothing to send: it is a terminal, an empty file, or a device such as
+// /dev/null, which cron jobs and services are given. A pipe is read up
+// to its first byte, so an empty pipe sends no body either.
+func stdinBody() io.Reader {
+	if isTerminal(os.Stdin) {
+		return nil
+	}
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		// stdin may be closed, as for some Windows services
+		return nil
+	}
+	mode := fi.Mode()
+	switch {
+	case mode.IsRegular():
+		if fi.Size() == 0 {
+			return nil
+		}
+		// the file itself is kept, so its length is sent
+		return os.Stdin
+	case mode&(os.ModeNamedPipe|os.ModeSocket) != 0:
+		r := bufio.NewReader(os.Stdin)
+		if _, err := r.Peek(1); err == io.EOF {
+			return nil
+		} else if err != nil {
+			log.Fatal("Read from stdin ", err)
+		}
+		return r
+	}
+	return nil
+}
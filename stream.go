@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// recordSeparator starts each record of an application/json-seq stream
+const recordSeparator = 0x1e
+
+// streaming reports whether the response body is printed as it arrives.
+// A body checked with -hmac.verify has already been read in full.
+func streaming(res *http.Response) bool {
+	return !hmacVerify && (stream || isEventStream(res))
+}
+
+// streamResponse prints the response body as it arrives. Event streams
+// and JSON record streams are printed one event or record at a time,
+// anything else chunk by chunk.
+func streamResponse(httpreq *httplib.BeegoHttpRequest, res *http.Response) {
+	if isEventStream(res) {
+		streamEvents(httpreq, res)
+		return
+	}
+	defer res.Body.Close()
+	if rawEncoding {
+		if _, err := io.Copy(os.Stdout, res.Body); err != nil {
+			log.Fatal("can't read response ", err)
+		}
+		return
+	}
+	body, err := httplib.DecodedBody(res)
+	if err != nil {
+		log.Fatal("can't decode response ", err)
+	}
+
+	mediatype, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	switch mediatype {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		err = streamRecords(body, bufio.ScanLines, "")
+	case "application/json-seq":
+		err = streamRecords(body, scanRecords, "\x1e")
+	default:
+		_, err = io.Copy(os.Stdout, body)
+	}
+	if err != nil {
+		log.Fatal("can't read response ", err)
+	}
+}
+
+// streamRecords prints each JSON record as it is read. On a terminal,
+// records are pretty printed and colorized, and otherwise written out
+// unchanged after prefix, so the stream can be piped on, though colored
+// with -color=always.
+func streamRecords(r io.Reader, split bufio.SplitFunc, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	scanner.Split(split)
+	for scanner.Scan() {
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+		if !interactive {
+			// colors, if forced, keep the record on one line
+			str := string(record)
+			if palette != nil && json.Valid(record) {
+				str = ColorfulJson(str)
+			}
+			fmt.Printf("%s%s\n", prefix, str)
+			continue
+		}
+		str, err := formatBody(record, "application/json", pretty)
+		if err == nil && json.Valid(record) {
+			str = ColorfulJson(str)
+		}
+		fmt.Println(str)
+	}
+	return scanner.Err()
+}
+
+// scanRecords splits RFC 7464 JSON text sequences on the record
+// separator, leaving whitespace to the caller.
+func scanRecords(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	if len(data) > 0 && data[0] == recordSeparator {
+		start = 1
+	}
+	if i := bytes.IndexByte(data[start:], recordSeparator); i >= 0 {
+		return start + i, data[start : start+i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data[start:], nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanRecords(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "records", input: "\x1e{\"a\":1}\n\x1e{\"b\":2}\n", want: []string{"{\"a\":1}\n", "{\"b\":2}\n"}},
+		{name: "no separator at the start", input: "1\n\x1e2\n", want: []string{"1\n", "2\n"}},
+		{name: "empty records", input: "\x1e\x1e1", want: []string{"", "1"}},
+		{name: "unterminated record", input: "\x1e{\"a\":", want: []string{"{\"a\":"}},
+		{name: "empty", input: "", want: nil},
+	}
+
+	for _, tc := range testCases {
+		scanner := bufio.NewScanner(strings.NewReader(tc.input))
+		// a small buffer splits records across reads
+		scanner.Buffer(make([]byte, 2), 1024)
+		scanner.Split(scanRecords)
+		var got []string
+		for scanner.Scan() {
+			got = append(got, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+		if !cmp.Equal(tc.want, got) {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestStreamRecords(t *testing.T) {
+	defer func(p theme, i bool) { palette, interactive = p, i }(palette, interactive)
+	palette, interactive = nil, false
+
+	testCases := []struct {
+		name   string
+		input  string
+		split  bufio.SplitFunc
+		prefix string
+		want   string
+	}{
+		{
+			name:  "ndjson",
+			input: "{\"a\":1}\n\n  {\"b\":2}  \n",
+			split: bufio.ScanLines,
+			want:  "{\"a\":1}\n{\"b\":2}\n",
+		},
+		{
+			name:  "ndjson with crlf",
+			input: "1\r\n2\r\n",
+			split: bufio.ScanLines,
+			want:  "1\n2\n",
+		},
+		{
+			name:   "json-seq",
+			input:  "\x1e{\"a\":1}\n\x1e\n\x1e[2]\n",
+			split:  scanRecords,
+			prefix: "\x1e",
+			want:   "\x1e{\"a\":1}\n\x1e[2]\n",
+		},
+		{
+			name:  "invalid json is printed as it is",
+			input: "{\"a\":\n",
+			split: bufio.ScanLines,
+			want:  "{\"a\":\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		var err error
+		got := captureStdout(t, func() {
+			err = streamRecords(strings.NewReader(tc.input), tc.split, tc.prefix)
+		})
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%v: printed %q, wanted %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	defer func(s, v bool) { stream, hmacVerify = s, v }(stream, hmacVerify)
+
+	testCases := []struct {
+		name        string
+		contentType string
+		stream      bool
+		verify      bool
+		want        bool
+	}{
+		{name: "event stream", contentType: "text/event-stream; charset=utf-8", want: true},
+		{name: "json", contentType: "application/json"},
+		{name: "json with -stream", contentType: "application/json", stream: true, want: true},
+		{name: "ndjson with -stream", contentType: "application/x-ndjson", stream: true, want: true},
+		{name: "verified event stream", contentType: "text/event-stream", verify: true},
+		{name: "verified with -stream", contentType: "application/json", stream: true, verify: true},
+	}
+
+	for _, tc := range testCases {
+		stream, hmacVerify = tc.stream, tc.verify
+		res := &http.Response{Header: http.Header{"Content-Type": {tc.contentType}}}
+		if got := streaming(res); got != tc.want {
+			t.Errorf("%v: streaming %v, wanted %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+// theme maps each of the named colors to the SGR parameters that draw
+// it, so a theme can use 256 colors or truecolor, or only emphasis. A
+// color with no parameters is left plain.
+type theme map[uint8]string
+
+var themes = map[string]theme{
+	"default": {
+		Gray: "90", Red: "91", Green: "92", Yellow: "93",
+		Blue: "94", Magenta: "95", Cyan: "96", White: "97",
+	},
+	"monokai": {
+		Gray: "38;5;242", Red: "38;5;197", Green: "38;5;148", Yellow: "38;5;141",
+		Blue: "38;5;81", Magenta: "38;5;197", Cyan: "38;5;186", White: "38;5;231",
+	},
+	"solarized": {
+		Gray: "38;2;88;110;117", Red: "38;2;220;50;47", Green: "38;2;133;153;0",
+		Yellow: "38;2;181;137;0", Blue: "38;2;38;139;210", Magenta: "38;2;211;54;130",
+		Cyan: "38;2;42;161;152", White: "38;2;238;232;213",
+	},
+	"dracula": {
+		Gray: "38;2;98;114;164", Red: "38;2;255;85;85", Green: "38;2;80;250;123",
+		Yellow: "38;2;241;250;140", Blue: "38;2;189;147;249", Magenta: "38;2;255;121;198",
+		Cyan: "38;2;139;233;253", White: "38;2;248;248;242",
+	},
+	"mono": {
+		Gray: "2", Red: "1", Magenta: "1",
+	},
+	"none": nil,
+}
+
+// palette is the theme in use, or nil when colors are turned off
+var palette = themes["default"]
+
+// setStyle selects the -style theme, which -color may then turn off
+func setStyle(name string) {
+	t, ok := themes[name]
+	if !ok {
+		var names []string
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		log.Fatalf("unknown style %q, use one of %s", name, strings.Join(names, ", "))
+	}
+	palette = t
+}
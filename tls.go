@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"os"
+)
+
+// clientTLSConfig returns the TLS config for -cert, -ca and -insecure,
+// or nil to use the defaults
+func clientTLSConfig() *tls.Config {
+	if certFile == "" && caFile == "" && !insecureSSL {
+		return nil
+	}
+	c := &tls.Config{InsecureSkipVerify: insecureSSL}
+	if certFile != "" {
+		keyFile := certKeyFile
+		if keyFile == "" {
+			keyFile = certFile
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Fatal("can't load the client certificate ", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			log.Fatal("can't read the CA certificates ", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			log.Fatal("no CA certificates found in ", caFile)
+		}
+		c.RootCAs = pool
+	}
+	return c
+}
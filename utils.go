@@ -14,6 +14,24 @@ func inSlice(str string, l []string) bool {
 	return false
 }
 
+// hasScheme reports whether rawurl starts with one of the given schemes
+func hasScheme(rawurl string, schemes ...string) bool {
+	i := strings.Index(rawurl, "://")
+	return i > 0 && inSlice(strings.ToLower(rawurl[:i]), schemes)
+}
+
+// stringList collects the values of a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 // Convert bytes to human readable string. Like a 2 MB, 64.2 KB, 52 B
 func FormatBytes(i int64) (result string) {
 	switch {
@@ -31,3 +49,21 @@ func FormatBytes(i int64) (result string) {
 	result = strings.Trim(result, " ")
 	return
 }
+
+// expandURL adds the http scheme to a URL without one, and localhost to
+// the :port/path shorthand
+func expandURL(rawurl string) string {
+	if strings.HasPrefix(rawurl, ":") {
+		if rawurl == ":" {
+			rawurl = "http://localhost/"
+		} else if len(rawurl) > 1 && rawurl[1] != '/' {
+			rawurl = "http://localhost" + rawurl
+		} else {
+			rawurl = "http://localhost" + rawurl[1:]
+		}
+	}
+	if !hasScheme(rawurl, "http", "https", "ws", "wss") {
+		rawurl = "http://" + rawurl
+	}
+	return rawurl
+}
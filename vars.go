@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// varPattern matches {{name}}, or \{{name}} to send it as it is, while
+// other uses of braces, such as the {{.Params.id}} templates of mock
+// routes, are left alone
+var varPattern = regexp.MustCompile(`\\?\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// variables are those of the environment selected with -env
+var variables map[string]string
+
+// maxVarDepth limits variables defined in terms of others, so a cycle
+// is an error rather than a hang
+const maxVarDepth = 8
+
+// expandVars replaces each {{name}} in s with the variable of the
+// environment, or with a built in value: {{uuid}}, {{now}},
+// {{randomInt}} or {{env.VAR}}, from the process environment. Each use
+// of a dynamic value is a new one. Without an environment, other names
+// are left as they are, so existing requests that send braces still do.
+func expandVars(s string) (string, error) {
+	return expandDepth(s, 0)
+}
+
+func expandDepth(s string, depth int) (string, error) {
+	if depth > maxVarDepth {
+		return "", fmt.Errorf("variables nest too deeply in %q", s)
+	}
+	var err error
+	out := varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if err != nil {
+			return m
+		}
+		if m[0] == '\\' {
+			return m[1:]
+		}
+		name := varPattern.FindStringSubmatch(m)[1]
+		v, ok, lerr := lookupVar(name, depth)
+		if lerr != nil {
+			err = lerr
+		} else if !ok && environment != "" {
+			err = fmt.Errorf("unknown variable {{%s}} in environment %q", name, environment)
+		} else if !ok {
+			return m
+		}
+		return v
+	})
+	return out, err
+}
+
+// lookupVar returns the value of the variable, and false if there is
+// no variable with the name
+func lookupVar(name string, depth int) (string, bool, error) {
+	// an environment's values may use other variables, such as secrets
+	// kept in {{env.VAR}}
+	if v, ok := variables[name]; ok {
+		v, err := expandDepth(v, depth+1)
+		return v, true, err
+	}
+	switch name {
+	case "uuid":
+		v, err := newUUID()
+		return v, true, err
+	case "now":
+		return time.Now().UTC().Format(time.RFC3339), true, nil
+	case "randomInt":
+		n, err := rand.Int(rand.Reader, big.NewInt(1000))
+		if err != nil {
+			return "", true, err
+		}
+		return n.String(), true, nil
+	}
+	if key := strings.TrimPrefix(name, "env."); key != name {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			return "", true, fmt.Errorf("{{%s}}: %s is not set", name, key)
+		}
+		return v, true, nil
+	}
+	return "", false, nil
+}
+
+// newUUID returns a random, version 4, UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
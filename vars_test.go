@@ -0,0 +1,103 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// withEnvironment selects an environment of variables for the test
+func withEnvironment(t *testing.T, name string, vars map[string]string) {
+	savedEnv, savedVars := environment, variables
+	t.Cleanup(func() { environment, variables = savedEnv, savedVars })
+	environment, variables = name, vars
+}
+
+func TestExpandVars(t *testing.T) {
+	t.Setenv("GURL_TEST_TOKEN", "s3cr3t")
+	testCases := []struct {
+		name    string
+		env     string
+		vars    map[string]string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "variable", env: "dev", vars: map[string]string{"host": "localhost"}, input: "{{host}}/a", want: "localhost/a"},
+		{name: "spaces", env: "dev", vars: map[string]string{"host": "localhost"}, input: "{{ host }}", want: "localhost"},
+		{name: "nested", env: "dev", vars: map[string]string{"base": "{{host}}/v1", "host": "h"}, input: "{{base}}/x", want: "h/v1/x"},
+		{name: "process environment", input: "Bearer {{env.GURL_TEST_TOKEN}}", want: "Bearer s3cr3t"},
+		{name: "variable from the process environment", env: "dev", vars: map[string]string{"token": "{{env.GURL_TEST_TOKEN}}"}, input: "{{token}}", want: "s3cr3t"},
+		{name: "unset process environment", input: "{{env.GURL_TEST_UNSET}}", wantErr: true},
+		{name: "undefined without an environment", input: "{{ nope }}", want: "{{ nope }}"},
+		{name: "undefined in an environment", env: "dev", vars: map[string]string{}, input: "{{nope}}", wantErr: true},
+		{name: "escaped", env: "dev", vars: map[string]string{"host": "h"}, input: `\{{host}} {{host}}`, want: "{{host}} h"},
+		{name: "escaped built in", input: `\{{uuid}}`, want: "{{uuid}}"},
+		{name: "templates left alone", input: "{{.Params.id}} {{ index . 0 }}", want: "{{.Params.id}} {{ index . 0 }}"},
+		{name: "environment over built in", env: "dev", vars: map[string]string{"now": "then"}, input: "{{now}}", want: "then"},
+		{name: "cycle", env: "dev", vars: map[string]string{"a": "{{b}}", "b": "{{a}}"}, input: "{{a}}", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		withEnvironment(t, tc.env, tc.vars)
+		got, err := expandVars(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("%v: diff %v", tc.name, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestExpandDynamicVars(t *testing.T) {
+	withEnvironment(t, "", nil)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	got, err := expandVars("{{uuid}} {{uuid}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := strings.Fields(got)
+	if len(ids) != 2 || !uuid.MatchString(ids[0]) || !uuid.MatchString(ids[1]) || ids[0] == ids[1] {
+		t.Errorf("unexpected uuids %q", got)
+	}
+
+	got, err = expandVars("{{now}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now, err := time.Parse(time.RFC3339, got); err != nil || time.Since(now) > time.Minute {
+		t.Errorf("unexpected time %q %v", got, err)
+	}
+
+	for i := 0; i < 20; i++ {
+		got, err = expandVars("{{randomInt}}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := strconv.Atoi(got); err != nil || n < 0 || n >= 1000 {
+			t.Fatalf("unexpected random int %q %v", got, err)
+		}
+	}
+}
+
+func TestParseItemsVars(t *testing.T) {
+	withEnvironment(t, "dev", map[string]string{"id": "42"})
+	got := parseItems([]string{"id={{id}}", `X-A:{{ id }}`, `raw=\{{id}}`})
+	want := []requestItem{{"id", "=", "42"}, {"X-A", ":", "42"}, {"raw", "=", "{{id}}"}}
+	if !cmp.Equal(want, got, cmp.AllowUnexported(requestItem{})) {
+		t.Errorf("diff %v", cmp.Diff(want, got, cmp.AllowUnexported(requestItem{})))
+	}
+
+	withEnvironment(t, "", nil)
+	got = parseItems([]string{`X-A:{{ unknown }}`, `id=\{{uuid}}`})
+	want = []requestItem{{"X-A", ":", "{{ unknown }}"}, {"id", "=", "{{uuid}}"}}
+	if !cmp.Equal(want, got, cmp.AllowUnexported(requestItem{})) {
+		t.Errorf("diff %v", cmp.Diff(want, got, cmp.AllowUnexported(requestItem{})))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "os"
+
+// enableVirtualTerminal is only needed on Windows, as other terminals
+// interpret escape sequences already.
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/skunkwerks/gurl/httplib"
+)
+
+// closeGrace is how long to wait for the server to answer a close frame
+const closeGrace = 2 * time.Second
+
+// isWebsocket reports whether the URL asks for a WebSocket session
+func isWebsocket(scheme string) bool {
+	return scheme == "ws" || scheme == "wss"
+}
+
+// handshakeHeaders are set by the dialer, which refuses duplicates, and
+// the others make no sense for an upgrade without a body.
+var handshakeHeaders = []string{
+	"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version",
+	"Sec-Websocket-Extensions", "Accept-Encoding", "Content-Type",
+}
+
+// websocketSession upgrades the prepared request, with the same headers,
+// TLS and proxy settings as any other request, then prints each message
+// received, and sends each line of input as a text message. When input
+// ends, or on interrupt, the connection is closed cleanly.
+func websocketSession(httpreq *httplib.BeegoHttpRequest, input io.Reader) {
+	for _, h := range handshakeHeaders {
+		httpreq.GetRequest().Header.Del(h)
+	}
+	req, err := httpreq.Prepare()
+	if err != nil {
+		log.Fatal("can't prepare the handshake ", err)
+	}
+	header := req.Header.Clone()
+	if req.Host != "" {
+		header.Set("Host", req.Host)
+	}
+
+	setting := httpreq.GetSetting()
+	dialer := websocket.Dialer{
+		// an idle session must not time out between messages
+		NetDial:           httplib.TimeoutDialer(setting.ConnectTimeout, 0),
+		Proxy:             setting.Proxy,
+		TLSClientConfig:   setting.TlsClientConfig,
+		HandshakeTimeout:  setting.ConnectTimeout,
+		EnableCompression: true,
+	}
+	if printOption&printReqHeader == printReqHeader {
+		printHandshake(strings.TrimRight(string(httpreq.DumpRequest()), "\r\n"))
+	}
+	conn, res, err := dialer.Dial(req.URL.String(), header)
+	if res != nil && printOption&printRespHeader == printRespHeader {
+		printHandshake(handshakeResponse(res))
+	}
+	if err != nil {
+		if res != nil && res.Body != nil {
+			body, _ := io.ReadAll(res.Body)
+			os.Stdout.Write(body)
+		}
+		log.Fatal("WebSocket handshake failed: ", err)
+	}
+	defer conn.Close()
+
+	conn.SetPingHandler(func(data string) error {
+		printNotice("ping " + data)
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(closeGrace))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+	conn.SetPongHandler(func(data string) error {
+		printNotice("pong " + data)
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			kind, msg, err := conn.ReadMessage()
+			if err != nil {
+				var closed *websocket.CloseError
+				if errors.As(err, &closed) {
+					printNotice(strings.TrimSpace(fmt.Sprintf("closed %d %s", closed.Code, closed.Text)))
+				} else {
+					printNotice(err.Error())
+				}
+				return
+			}
+			if printOption&printRespBody == printRespBody {
+				printMessage(kind, msg)
+			}
+		}
+	}()
+
+	if wsPing > 0 {
+		go func() {
+			for range time.Tick(wsPing) {
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsPing)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	lines := make(chan []byte)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(nil, 64*1024*1024)
+		for scanner.Scan() {
+			lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+	}()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				closeSession(conn, done)
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, line); err != nil {
+				log.Fatal("can't send message ", err)
+			}
+		case <-interrupt:
+			closeSession(conn, done)
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// closeSession sends a close frame, and waits a while for the server to
+// answer with its own, so the last messages are still printed.
+func closeSession(conn *websocket.Conn, done chan struct{}) {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeGrace)); err != nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(closeGrace):
+	}
+}
+
+func handshakeResponse(res *http.Response) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s %s\n", res.Proto, res.Status)
+	for k, v := range res.Header {
+		fmt.Fprintf(&out, "%s: %s\n", k, strings.Join(v, " "))
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
+
+func printHandshake(str string) {
+	fmt.Println(ColorfulRequest(str))
+	fmt.Println("")
+}
+
+// printMessage pretty prints JSON text messages on a terminal, and shows
+// binary messages as a hex dump. Otherwise, text messages are written one
+// per line, and binary messages as they are.
+func printMessage(kind int, msg []byte) {
+	if kind == websocket.BinaryMessage {
+		if interactive {
+			printNotice(fmt.Sprintf("binary message, %d bytes", len(msg)))
+			fmt.Print(hex.Dump(msg))
+		} else {
+			os.Stdout.Write(msg)
+		}
+		return
+	}
+	if !interactive {
+		str := string(msg)
+		if palette != nil && json.Valid(msg) {
+			str = ColorfulJson(str)
+		}
+		fmt.Printf("%s\n", str)
+		return
+	}
+	trimmed := bytes.TrimSpace(msg)
+	if json.Valid(trimmed) {
+		if str, err := formatBody(trimmed, "application/json", pretty); err == nil {
+			fmt.Println(ColorfulJson(str))
+			return
+		}
+	}
+	fmt.Printf("%s\n", msg)
+}